@@ -6,7 +6,7 @@ import (
 
 	"github.com/antibaloo/sf-final-project/internal/api/censor"
 	"github.com/antibaloo/sf-final-project/internal/config"
-	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+	"github.com/antibaloo/sf-final-project/internal/storage/factory"
 )
 
 func main() {
@@ -17,13 +17,13 @@ func main() {
 		return
 	}
 	// Подключаемся к БД
-	db, err := postgres.NewStore(config.ConString())
+	db, err := factory.NewStore(config)
 	if err != nil {
 		fmt.Printf("%v: ошибка при соединении с БД: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
 	}
 	// Создаем сервис проверки комментариев
-	censorServer, err := censor.CreateService(config.CensorAddress(), db)
+	censorServer, err := censor.CreateService(config.CensorAddress(), config.RequestTimeout(), db)
 	if err != nil {
 		fmt.Printf("%v: ошибка при создании сервиса проверки комментариев: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
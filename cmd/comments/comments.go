@@ -6,7 +6,7 @@ import (
 
 	"github.com/antibaloo/sf-final-project/internal/api/comments"
 	"github.com/antibaloo/sf-final-project/internal/config"
-	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+	"github.com/antibaloo/sf-final-project/internal/storage/factory"
 )
 
 func main() {
@@ -15,13 +15,13 @@ func main() {
 		fmt.Printf("%v: ошибка при запуске сервиса комментариев: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
 	}
-	db, err := postgres.NewStore(config.ConString())
+	db, err := factory.NewStore(config)
 	if err != nil {
 		fmt.Printf("%v: ошибка при соединении сервиса комменатриев с БД: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
 	}
 
-	commentsServer, err := comments.CreateService(config.CommentsAddress(), db)
+	commentsServer, err := comments.CreateService(config.CommentsAddress(), config.RequestTimeout(), db)
 	if err != nil {
 		fmt.Printf("%v: ошибка при создании сервиса комменатриев: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
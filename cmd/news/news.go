@@ -1,13 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/antibaloo/sf-final-project/internal/api/news"
 	"github.com/antibaloo/sf-final-project/internal/config"
 	"github.com/antibaloo/sf-final-project/internal/rss"
-	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+	"github.com/antibaloo/sf-final-project/internal/storage/factory"
 )
 
 func main() {
@@ -17,12 +18,27 @@ func main() {
 		return
 	}
 
-	db, err := postgres.NewStore(config.ConString())
+	db, err := factory.NewStore(config)
 	if err != nil {
 		fmt.Printf("%v: ошбика при боключении сервиса новостей к БД: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
 	}
-	defer db.Pool.Close()
+	defer db.Close()
+
+	// Ссылки новостей, сохраненных до появления канонических ссылок, приводим к каноническому виду до первого
+	// опроса источников, иначе те же новости будут сохранены повторно. Нужно только хранилищу PostgreSQL.
+	if backfill, ok := db.(interface {
+		CanonicalizeLinks(ctx context.Context) (int, error)
+	}); ok {
+		updated, err := backfill.CanonicalizeLinks(context.Background())
+		if err != nil {
+			fmt.Printf("%v: ошибка при приведении ссылок новостей к каноническому виду: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
+			return
+		}
+		if updated > 0 {
+			fmt.Printf("%v: ссылки новостей приведены к каноническому виду: %d\n", time.Now().Format("02.01.2006 15:04:05 MST"), updated)
+		}
+	}
 
 	// Создаем ридер новостей
 	rssReader, err := rss.CreateService(config.RssConfig(), db)
@@ -31,11 +47,20 @@ func main() {
 		return
 	}
 
-	// Запускаем ридер новостей
-	rssReader.Start()
+	// Запускаем ридер новостей. Сервис новостей останавливается по сигналу, после этого останавливаем ридер:
+	// опросы прекращаются, начатая запись новостей в БД завершается до закрытия соединения с БД
+	rssReader.Start(context.Background())
+	defer rssReader.Stop()
 
 	// Создаем сервис новостей
-	newsServer, err := news.CreateService(config.NewsAddress(), config.NewsPerPage(), db)
+	newsServer, err := news.CreateService(
+		config.NewsAddress(),
+		config.NewsPerPage(),
+		config.NewsPerPageMin(),
+		config.NewsPerPageMax(),
+		config.RequestTimeout(),
+		db,
+	)
 	if err != nil {
 		fmt.Printf("%v: ошибка при создании сервиса новостей: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), err.Error())
 		return
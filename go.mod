@@ -2,13 +2,9 @@ module github.com/antibaloo/sf-final-project
 
 go 1.22.3
 
-require github.com/jackc/pgx/v5 v5.7.1
-
 require (
-	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
-	github.com/jackc/pgconn v1.14.3 // indirect
-	github.com/jackc/pgio v1.0.0 // indirect
-	github.com/jackc/pgproto3/v2 v2.3.3 // indirect
+	github.com/jackc/pgx/v5 v5.7.1
+	golang.org/x/net v0.29.0
 )
 
 require (
@@ -18,5 +14,5 @@ require (
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
-	golang.org/x/text v0.18.0 // indirect
+	golang.org/x/text v0.18.0
 )
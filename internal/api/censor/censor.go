@@ -20,11 +20,12 @@ type censor struct {
 	dictionary []string      // Словать запрещенных слов
 	address    string        // адрес на котором будет запущен сервис
 	db         storage.Store // база данных сервиса
+	timeout    time.Duration // предельное время обработки запроса к БД
 	httpServer *http.Server  // веб-сервер сервиса
 }
 
 // Конструктор сервиса проверки комментариев
-func CreateService(address string, db storage.Store) (*censor, error) {
+func CreateService(address string, timeout time.Duration, db storage.Store) (*censor, error) {
 	if address == "" {
 		return nil, fmt.Errorf("не указан адрес запуска сервиса")
 	}
@@ -35,6 +36,7 @@ func CreateService(address string, db storage.Store) (*censor, error) {
 		dictionary: []string{},
 		address:    address,
 		db:         db,
+		timeout:    timeout,
 	}, nil
 }
 
@@ -43,7 +45,9 @@ func (censor *censor) Start() error {
 	fmt.Printf("%v: запускаем сервис комментариев по адресу: %s\n", time.Now().Format("02.01.2006 15:04:05 MST"), censor.address)
 
 	// Загружаем словарь из БД
-	dictionary, err := censor.db.Dictionary()
+	ctx, cancel := context.WithTimeout(context.Background(), censor.timeout)
+	defer cancel()
+	dictionary, err := censor.db.Dictionary(ctx)
 	if err != nil {
 		return err
 	}
@@ -20,11 +20,12 @@ type commentsService struct {
 	address    string               // адрес на котором будет запущен сервис
 	modCh      chan storage.Comment // канала связи с горутиной модератора комментариев
 	db         storage.Store        // база данных сервиса
+	timeout    time.Duration        // предельное время обработки запроса к БД
 	httpServer *http.Server         // веб-сервер сервиса
 }
 
 // Конструктор структуры сервиса комментариев
-func CreateService(address string, db storage.Store) (*commentsService, error) {
+func CreateService(address string, timeout time.Duration, db storage.Store) (*commentsService, error) {
 	if address == "" {
 		return nil, fmt.Errorf("не указан адрес запуска сервиса")
 	}
@@ -34,6 +35,7 @@ func CreateService(address string, db storage.Store) (*commentsService, error) {
 	return &commentsService{
 		address: address,
 		db:      db,
+		timeout: timeout,
 		modCh:   make(chan storage.Comment),
 	}, nil
 }
@@ -74,7 +76,9 @@ func (comments *commentsService) getCommentsByNewsIdHandler(w http.ResponseWrite
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	commentsByNewsId, err := comments.db.CommentsByNewsId(id)
+	ctx, cancel := context.WithTimeout(r.Context(), comments.timeout)
+	defer cancel()
+	commentsByNewsId, err := comments.db.CommentsByNewsId(ctx, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -97,7 +101,9 @@ func (comments *commentsService) addCommentHandler(w http.ResponseWriter, r *htt
 		return
 	}
 	// Добавляем комментарий в БД и проверяем на ошибки
-	err = comments.db.AddComment(comment)
+	ctx, cancel := context.WithTimeout(r.Context(), comments.timeout)
+	defer cancel()
+	err = comments.db.AddComment(ctx, comment)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -79,7 +79,7 @@ func (api *apiGateway) Start() error {
 // Обработчик получения списка новостей
 func (api *apiGateway) newsHandler(w http.ResponseWriter, r *http.Request) {
 	// Перенаправляем запрос по адресу сервиса новостей
-	resp, err := http.Get("http://" + api.newsAddress + r.URL.RequestURI())
+	resp, err := get(r.Context(), "http://"+api.newsAddress+r.URL.RequestURI())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -91,7 +91,8 @@ func (api *apiGateway) newsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	// Возвращаем клиенту код и тело ответа от сервиса новостей
+	// Возвращаем клиенту код, тип и тело ответа от сервиса новостей
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
 	w.WriteHeader(resp.StatusCode)
 	w.Write(body)
 }
@@ -109,11 +110,11 @@ func (api *apiGateway) detailedNewsHandler(w http.ResponseWriter, r *http.Reques
 	// Запускаем ассинхронно запросы к сервисам новостей и комментариев
 	go func() {
 		defer wg.Done()
-		respNews, errNews = http.Get("http://" + api.newsAddress + r.URL.Path + "/detailed?" + r.URL.RawQuery)
+		respNews, errNews = get(r.Context(), "http://"+api.newsAddress+r.URL.Path+"/detailed?"+r.URL.RawQuery)
 	}()
 	go func() {
 		defer wg.Done()
-		respComments, errComments = http.Get("http://" + api.commentsAddres + r.URL.Path + "/comments?" + r.URL.RawQuery)
+		respComments, errComments = get(r.Context(), "http://"+api.commentsAddres+r.URL.Path+"/comments?"+r.URL.RawQuery)
 	}()
 
 	// Ждем пока отработают оба запроса
@@ -195,7 +196,7 @@ func (api *apiGateway) addCommentHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	// Отправляем полуяенный комментрий на проверку к сервису проверки
-	resp, err := http.Post("http://"+api.censorAddress+"/check?"+r.URL.RawQuery, "application/json", bytes.NewReader(body))
+	resp, err := post(r.Context(), "http://"+api.censorAddress+"/check?"+r.URL.RawQuery, body)
 	// Проверяем на ошибку запрос к сервису проверки комментариев
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -217,7 +218,7 @@ func (api *apiGateway) addCommentHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Если проверка пройдена, отправляем комментарий на публикацию
-	resp, err = http.Post("http://"+api.commentsAddres+r.URL.Path+"?"+r.URL.RawQuery, "application/json", bytes.NewReader(body))
+	resp, err = post(r.Context(), "http://"+api.commentsAddres+r.URL.Path+"?"+r.URL.RawQuery, body)
 	// Проверяем на ошибку запрос к сервису комментариев
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -235,3 +236,22 @@ func (api *apiGateway) addCommentHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(resp.StatusCode)
 	w.Write(body)
 }
+
+// Отправляет GET запрос к внутреннему сервису, запрос прерывается при отмене контекста клиента
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// Отправляет POST запрос с json телом к внутреннему сервису, запрос прерывается при отмене контекста клиента
+func post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(req)
+}
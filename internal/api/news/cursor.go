@@ -0,0 +1,101 @@
+package news
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Кодирует позицию новости в непрозрачный для клиента курсор
+func encodeCursor(pubTime int64, id int, before bool) string {
+	direction := "next"
+	if before {
+		direction = "prev"
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d:%d", direction, pubTime, id)))
+}
+
+// Раскодирует курсор, пустая строка означает начало списка
+func decodeCursor(s string) (*storage.Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный курсор")
+	}
+	var (
+		cursor    storage.Cursor
+		direction string
+	)
+	if _, err := fmt.Sscanf(string(b), "%4s:%d:%d", &direction, &cursor.PubTime, &cursor.Id); err != nil {
+		return nil, fmt.Errorf("некорректный курсор")
+	}
+	switch direction {
+	case "next":
+	case "prev":
+		cursor.Before = true
+	default:
+		return nil, fmt.Errorf("некорректный курсор")
+	}
+	return &cursor, nil
+}
+
+// Обработчик получения страницы списка новостей по курсору, страницы идут по убыванию (pub_time, id)
+func (n *newsService) cursorNewsHandler(w http.ResponseWriter, r *http.Request, q storage.NewsQuery) {
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeParamError(w, &paramError{Param: "cursor", Value: r.URL.Query().Get("cursor"), Message: err.Error()})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	news, more, err := n.db.NewsPage(ctx, cursor, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p := pagination{NewsPerPage: q.Limit}
+	switch {
+	// Первая страница: назад идти некуда
+	case cursor == nil:
+		if more {
+			last := news[len(news)-1]
+			p.NextCursor = encodeCursor(last.PubTime, last.Id, false)
+		}
+	// Шли назад: следующая страница есть всегда, предыдущая - если еще остались новости
+	case cursor.Before:
+		if len(news) > 0 {
+			first, last := news[0], news[len(news)-1]
+			p.NextCursor = encodeCursor(last.PubTime, last.Id, false)
+			if more {
+				p.PrevCursor = encodeCursor(first.PubTime, first.Id, true)
+			}
+		} else {
+			p.NextCursor = encodeCursor(cursor.PubTime, cursor.Id, false)
+		}
+	// Шли вперед: предыдущая страница есть всегда, следующая - если еще остались новости
+	default:
+		if len(news) > 0 {
+			first, last := news[0], news[len(news)-1]
+			p.PrevCursor = encodeCursor(first.PubTime, first.Id, true)
+			if more {
+				p.NextCursor = encodeCursor(last.PubTime, last.Id, false)
+			}
+		} else {
+			p.PrevCursor = encodeCursor(cursor.PubTime, cursor.Id, true)
+		}
+	}
+
+	// Возвращаем массив новостей с объектом паджинации
+	bytes, err := json.Marshal(newsResponse{News: news, Pagination: p})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
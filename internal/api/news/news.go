@@ -17,9 +17,11 @@ import (
 
 // Структура объекта паджинации
 type pagination struct {
-	NewsPerPage int `json:"news_per_page"` // Новостей на странице
-	Page        int `json:"page"`          // Текущая страница
-	Pages       int `json:"pages"`         // Всего страниц
+	NewsPerPage int    `json:"news_per_page"`         // Новостей на странице
+	Page        int    `json:"page"`                  // Текущая страница
+	Pages       int    `json:"pages"`                 // Всего страниц
+	NextCursor  string `json:"next_cursor,omitempty"` // Курсор следующей страницы (только при выводе по курсору)
+	PrevCursor  string `json:"prev_cursor,omitempty"` // Курсор предыдущей страницы (только при выводе по курсору)
 }
 
 // Структура для возвращения списка новостей с объектом паджинации
@@ -30,25 +32,34 @@ type newsResponse struct {
 
 // Структура сервиса новостей
 type newsService struct {
-	address     string
-	db          storage.Store
-	httpServer  *http.Server
-	newsPerPage int
+	address        string
+	db             storage.Store
+	httpServer     *http.Server
+	newsPerPage    int           // размер страницы по-умолчанию
+	newsPerPageMin int           // наименьший размер страницы, который может запросить клиент
+	newsPerPageMax int           // наибольший размер страницы, который может запросить клиент
+	timeout        time.Duration // предельное время обработки запроса к БД
 }
 
 // Конструктор структуры сервиса новостей
-func CreateService(address string, n int, db storage.Store) (*newsService, error) {
+func CreateService(address string, n, minPerPage, maxPerPage int, timeout time.Duration, db storage.Store) (*newsService, error) {
 	if address == "" {
 		return nil, fmt.Errorf("не указан адрес запуска сервиса")
 	}
+	if minPerPage < 1 || n < minPerPage || n > maxPerPage {
+		return nil, fmt.Errorf("размер страницы по-умолчанию вне допустимых границ")
+	}
 	if db == nil {
 		return nil, fmt.Errorf("не указана база данных")
 	}
 
 	return &newsService{
-		address:     address,
-		newsPerPage: n,
-		db:          db,
+		address:        address,
+		newsPerPage:    n,
+		newsPerPageMin: minPerPage,
+		newsPerPageMax: maxPerPage,
+		timeout:        timeout,
+		db:             db,
 	}, nil
 }
 
@@ -57,6 +68,14 @@ func (news *newsService) Start() error {
 	router := http.NewServeMux()
 	router.HandleFunc("GET /news", news.newsHandler)
 	router.HandleFunc("GET /news/{id}/detailed", news.detailedNewsHandler)
+	router.HandleFunc("GET /feeds", news.feedsHandler)
+	router.HandleFunc("GET /sources", news.sourcesHandler)
+	router.HandleFunc("POST /sources", news.addSourceHandler)
+	router.HandleFunc("GET /sources/opml", news.exportOPMLHandler)
+	router.HandleFunc("POST /sources/opml", news.importOPMLHandler)
+	router.HandleFunc("GET /sources/{id}", news.sourceHandler)
+	router.HandleFunc("PATCH /sources/{id}", news.updateSourceHandler)
+	router.HandleFunc("DELETE /sources/{id}", news.deleteSourceHandler)
 	news.httpServer = &http.Server{
 		Addr:    news.address,
 		Handler: middleware.GenIdAndLogging(router),
@@ -84,12 +103,30 @@ func (n *newsService) newsHandler(w http.ResponseWriter, r *http.Request) {
 	var (
 		p            pagination     // Объект паджинации
 		page         int        = 1 // Значение по-умолчанию
-		offset       int
 		err          error
 		newsResponse newsResponse //Структура для возвращения списка новостей с объектом паджинации
 	)
-	// Читаем строку поиска
-	search := r.URL.Query().Get("search")
+	// Читаем параметры отбора и сортировки
+	q, err := newsQuery(r.URL.Query())
+	if err != nil {
+		writeParamError(w, err)
+		return
+	}
+	// Читаем размер страницы
+	q.Limit, err = parseLimit(r.URL.Query().Get("limit"), n.newsPerPage, n.newsPerPageMin, n.newsPerPageMax)
+	if err != nil {
+		writeParamError(w, err)
+		return
+	}
+	// Постраничный вывод по курсору вместо номера страницы
+	if r.URL.Query().Has("cursor") {
+		if q.FullText || q.Asc || q.Sort != "" && q.Sort != storage.SortPubTime {
+			writeParamError(w, &paramError{Param: "cursor", Message: "вывод по курсору поддерживает только сортировку по убыванию pub_time"})
+			return
+		}
+		n.cursorNewsHandler(w, r, q)
+		return
+	}
 
 	// Читаем номер страницы
 	pageParam := r.URL.Query().Get("page")
@@ -99,25 +136,27 @@ func (n *newsService) newsHandler(w http.ResponseWriter, r *http.Request) {
 		// строку в число при помощи пакета strconv
 		page, err = strconv.Atoi(pageParam)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeParamError(w, &paramError{Param: "page", Value: pageParam, Message: "ожидается целое число"})
 			return
 		}
 	}
 	// Инициализируем объект паджинации
-	p.NewsPerPage = n.newsPerPage
+	p.NewsPerPage = q.Limit
 	p.Page = page
 	// Рассчитываем смещение, если страница не первая
 	if page > 1 {
-		offset = (page - 1) * n.newsPerPage
+		q.Offset = (page - 1) * q.Limit
 	}
-	news, count, err := n.db.News(offset, n.newsPerPage, search)
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	news, count, err := n.db.News(ctx, q)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	//Заполняем поле объекта паджинации
-	p.Pages = count / n.newsPerPage
-	if count%n.newsPerPage != 0 {
+	p.Pages = count / q.Limit
+	if count%q.Limit != 0 {
 		p.Pages++
 	}
 	newsResponse.News = news
@@ -140,11 +179,26 @@ func (n *newsService) detailedNewsHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	news, err := n.db.NewsByID(id)
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	news, err := n.db.NewsByID(ctx, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// Предыдущие версии новости отдаются по запросу
+	switch revisions := r.URL.Query().Get("revisions"); revisions {
+	case "", "false":
+	case "true":
+		news.Revisions, err = n.db.NewsRevisions(ctx, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		writeParamError(w, &paramError{Param: "revisions", Value: revisions, Message: "ожидается true или false"})
+		return
+	}
 	// Возвращаем детальную новость
 	bytes, err := json.Marshal(news)
 	if err != nil {
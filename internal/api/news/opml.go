@@ -0,0 +1,263 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antibaloo/sf-final-project/internal/rss"
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+const (
+	maxOPMLSize       = 1 << 20          // Наибольший размер загружаемого файла OPML
+	opmlCheckTimeout  = 10 * time.Second // Предельное время загрузки одного фида при проверке
+	opmlCheckParallel = 8                // Количество одновременно загружаемых при проверке фидов
+)
+
+// Результаты проверки фида при импорте OPML
+const (
+	opmlNew           = "new"            // Новый фид, добавляется в источники
+	opmlDuplicate     = "duplicate"      // Фид уже есть в источниках или повторяется в файле
+	opmlInvalid       = "invalid"        // Адрес фида не указан или не является адресом http или https
+	opmlUnreachable   = "unreachable"    // Фид не удалось загрузить, только при dry_run
+	opmlUnknownFormat = "unknown_format" // Ответ источника не является фидом поддерживаемого формата, только при dry_run
+)
+
+// Документ OPML со списком подписок
+type opml struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Title   string    `xml:"head>title"`
+	Created string    `xml:"head>dateCreated,omitempty"`
+	Body    []outline `xml:"body>outline"`
+}
+
+// Элемент outline: фид, если указан xmlUrl, иначе папка с вложенными элементами
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+// Фид из файла OPML с результатом проверки
+type opmlFeed struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Category string `json:"category"`          // Путь папок фида, разделенный "/"
+	Status   string `json:"status"`            // Результат проверки: opmlNew, opmlDuplicate, opmlInvalid и др.
+	Message  string `json:"message,omitempty"` // Причина, по которой фид не добавляется
+	Id       int    `json:"id,omitempty"`      // Идентификатор добавленного источника
+}
+
+// Отчет об импорте OPML
+type opmlReport struct {
+	DryRun        bool       `json:"dry_run"`        // Фиды только проверены, источники не добавлены
+	New           int        `json:"new"`            // Новых фидов
+	Duplicate     int        `json:"duplicate"`      // Повторяющихся фидов
+	Invalid       int        `json:"invalid"`        // Фидов с неверным адресом
+	Unreachable   int        `json:"unreachable"`    // Недоступных фидов
+	UnknownFormat int        `json:"unknown_format"` // Фидов неизвестного формата
+	Feeds         []opmlFeed `json:"feeds"`
+}
+
+// Загружает новые фиды и отмечает недоступные и неизвестного формата, одновременно загружается
+// не больше opmlCheckParallel фидов, каждый не дольше opmlCheckTimeout
+func checkFeeds(ctx context.Context, feeds []opmlFeed) {
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, opmlCheckParallel)
+	)
+	for i := range feeds {
+		if feeds[i].Status != opmlNew {
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(feed *opmlFeed) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			ctx, cancel := context.WithTimeout(ctx, opmlCheckTimeout)
+			defer cancel()
+			err := rss.CheckFeed(ctx, feed.URL)
+			switch {
+			case errors.Is(err, rss.ErrUnknownFormat):
+				feed.Status, feed.Message = opmlUnknownFormat, err.Error()
+			case err != nil:
+				feed.Status, feed.Message = opmlUnreachable, err.Error()
+			}
+		}(&feeds[i])
+	}
+	wg.Wait()
+}
+
+// Собирает фиды из элементов outline, папки становятся категориями
+func collectFeeds(feeds []opmlFeed, outlines []outline, folders []string) []opmlFeed {
+	for _, o := range outlines {
+		title := strings.TrimSpace(o.Title)
+		if title == "" {
+			title = strings.TrimSpace(o.Text)
+		}
+		// Элемент без адреса и без типа фида - папка, пустые папки пропускаются
+		if o.XMLURL == "" && o.Type != "rss" && o.Type != "atom" {
+			path := folders
+			if title != "" {
+				path = append(slices.Clip(folders), title)
+			}
+			feeds = collectFeeds(feeds, o.Outlines, path)
+			continue
+		}
+		feeds = append(feeds, opmlFeed{
+			URL:      strings.TrimSpace(o.XMLURL),
+			Title:    title,
+			Category: strings.Join(folders, "/"),
+		})
+	}
+	return feeds
+}
+
+// Добавляет фид в папку по пути категории, недостающие папки создаются
+func addToFolder(outlines []outline, path []string, feed outline) []outline {
+	if len(path) == 0 {
+		return append(outlines, feed)
+	}
+	i := slices.IndexFunc(outlines, func(o outline) bool { return o.XMLURL == "" && o.Text == path[0] })
+	if i < 0 {
+		i = len(outlines)
+		outlines = append(outlines, outline{Text: path[0], Title: path[0]})
+	}
+	outlines[i].Outlines = addToFolder(outlines[i].Outlines, path[1:], feed)
+	return outlines
+}
+
+// Обработчик импорта источников из файла OPML. При dry_run=true фиды только проверяются, новые фиды
+// загружаются, чтобы отметить недоступные и неизвестного формата. Отчет содержит результат проверки каждого фида.
+func (n *newsService) importOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	var report opmlReport
+	switch dryRun := r.URL.Query().Get("dry_run"); dryRun {
+	case "", "false":
+	case "true":
+		report.DryRun = true
+	default:
+		writeParamError(w, &paramError{Param: "dry_run", Value: dryRun, Message: "ожидается true или false"})
+		return
+	}
+	var doc opml
+	if err := xml.NewDecoder(http.MaxBytesReader(w, r.Body, maxOPMLSize)).Decode(&doc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	sources, err := n.db.Sources(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	known := make(map[string]bool, len(sources))
+	for _, src := range sources {
+		known[src.URL] = true
+	}
+	seen := map[string]bool{} // Адреса фидов, уже встреченные в файле
+	report.Feeds = collectFeeds([]opmlFeed{}, doc.Body, nil)
+	for i := range report.Feeds {
+		feed := &report.Feeds[i]
+		src := storage.Source{Enabled: true}
+		err := sourceRequest{URL: &feed.URL, Title: &feed.Title, Category: &feed.Category}.apply(&src)
+		var pe *paramError
+		switch {
+		case errors.As(err, &pe):
+			feed.Status, feed.Message = opmlInvalid, pe.Message
+		case seen[feed.URL]:
+			feed.Status, feed.Message = opmlDuplicate, "фид повторяется в файле"
+		case known[feed.URL]:
+			seen[feed.URL] = true
+			feed.Status, feed.Message = opmlDuplicate, storage.ErrDuplicateSource.Error()
+		default:
+			seen[feed.URL] = true
+			feed.Status = opmlNew
+			if report.DryRun {
+				break
+			}
+			// Источник мог быть добавлен одновременно другим запросом
+			feed.Id, err = n.db.AddSource(ctx, src)
+			if errors.Is(err, storage.ErrDuplicateSource) {
+				feed.Status, feed.Message = opmlDuplicate, err.Error()
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if report.DryRun {
+		checkFeeds(r.Context(), report.Feeds)
+	}
+	for _, feed := range report.Feeds {
+		switch feed.Status {
+		case opmlNew:
+			report.New++
+		case opmlDuplicate:
+			report.Duplicate++
+		case opmlInvalid:
+			report.Invalid++
+		case opmlUnreachable:
+			report.Unreachable++
+		case opmlUnknownFormat:
+			report.UnknownFormat++
+		}
+	}
+	bytes, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+// Обработчик выгрузки источников в файл OPML, категории становятся папками
+func (n *newsService) exportOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	sources, err := n.db.Sources(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc := opml{
+		Version: "2.0",
+		Title:   "Источники новостей",
+		Created: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	for _, src := range sources {
+		// Атрибут text обязателен, источник без названия подписывается адресом
+		feed := outline{Text: src.Title, Title: src.Title, Type: "rss", XMLURL: src.URL, HTMLURL: src.SiteURL}
+		if feed.Text == "" {
+			feed.Text = src.URL
+		}
+		path := strings.FieldsFunc(src.Category, func(r rune) bool { return r == '/' })
+		doc.Body = addToFolder(doc.Body, path, feed)
+	}
+	bytes, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sources.opml"`)
+	w.Write([]byte(xml.Header))
+	w.Write(bytes)
+}
@@ -0,0 +1,64 @@
+package news
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antibaloo/sf-final-project/internal/storage/memory"
+)
+
+func TestImportOPMLDryRun(t *testing.T) {
+	feeds := http.NewServeMux()
+	feeds.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Фид</title></channel></rss>`))
+	})
+	feeds.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Не фид</body></html>"))
+	})
+	server := httptest.NewServer(feeds)
+	defer server.Close()
+
+	db := memory.NewStore()
+	n, err := CreateService("localhost:0", 10, 1, 100, time.Second, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := `<opml version="2.0"><body><outline text="Папка">
+		<outline text="Фид" xmlUrl="` + server.URL + `/feed"/>
+		<outline text="Страница" xmlUrl="` + server.URL + `/page"/>
+		<outline text="Нет" xmlUrl="` + server.URL + `/missing"/>
+		<outline text="Повтор" xmlUrl="` + server.URL + `/feed"/>
+		<outline text="Неверный" xmlUrl="ftp://example.com/feed"/>
+	</outline></body></opml>`
+	request := httptest.NewRequest(http.MethodPost, "/sources/opml?dry_run=true", strings.NewReader(doc))
+	response := httptest.NewRecorder()
+	n.importOPMLHandler(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("код ответа %d: %s", response.Code, response.Body)
+	}
+	var report opmlReport
+	if err := json.Unmarshal(response.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{opmlNew, opmlUnknownFormat, opmlUnreachable, opmlDuplicate, opmlInvalid}
+	if len(report.Feeds) != len(want) {
+		t.Fatalf("фиды %+v, ожидались статусы %q", report.Feeds, want)
+	}
+	for i, status := range want {
+		if report.Feeds[i].Status != status {
+			t.Errorf("фид %s: статус %q, ожидался %q", report.Feeds[i].URL, report.Feeds[i].Status, status)
+		}
+	}
+	if !report.DryRun || report.New != 1 || report.UnknownFormat != 1 || report.Unreachable != 1 || report.Duplicate != 1 || report.Invalid != 1 {
+		t.Errorf("отчет %+v", report)
+	}
+	// При проверке источники не добавляются
+	if sources, err := db.Sources(request.Context()); err != nil || len(sources) != 0 {
+		t.Errorf("источники после проверки: %+v, %v", sources, err)
+	}
+}
@@ -0,0 +1,159 @@
+package news
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Режимы поиска новостей
+const (
+	searchSubstring = "substring" // Поиск по вхождению строки в заголовок
+	searchFullText  = "fulltext"  // Полнотекстовый поиск по заголовку и тексту с ранжированием
+)
+
+// Ошибка в параметре запроса, возвращается клиенту в виде json
+type paramError struct {
+	Param   string `json:"param"`         // Имя параметра
+	Value   string `json:"value"`         // Переданное значение
+	Message string `json:"message"`       // Описание ошибки
+	Min     int    `json:"min,omitempty"` // Наименьшее допустимое значение, для числовых параметров
+	Max     int    `json:"max,omitempty"` // Наибольшее допустимое значение, для числовых параметров
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("некорректный параметр %s: %s", e.Param, e.Message)
+}
+
+// Отвечает клиенту кодом 400 с описанием ошибки в параметре запроса
+func writeParamError(w http.ResponseWriter, err error) {
+	var pe *paramError
+	if !errors.As(err, &pe) {
+		pe = &paramError{Message: err.Error()}
+	}
+	bytes, err := json.Marshal(struct {
+		Error *paramError `json:"error"`
+	}{pe})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(bytes)
+}
+
+// Разбирает размер страницы, пустое значение - размер по-умолчанию
+func parseLimit(s string, def, minLimit, maxLimit int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, &paramError{Param: "limit", Value: s, Message: "ожидается целое число", Min: minLimit, Max: maxLimit}
+	}
+	if limit < minLimit || limit > maxLimit {
+		return 0, &paramError{Param: "limit", Value: s, Message: "значение вне допустимых границ", Min: minLimit, Max: maxLimit}
+	}
+	return limit, nil
+}
+
+// Разбирает параметры запроса списка новостей в параметры выборки, кроме постраничного вывода
+func newsQuery(values url.Values) (storage.NewsQuery, error) {
+	var (
+		q   storage.NewsQuery
+		err error
+	)
+	// Читаем строку поиска
+	q.Search = values.Get("search")
+	// Читаем режим поиска: по вхождению в заголовок (по-умолчанию) или полнотекстовый
+	switch mode := values.Get("mode"); mode {
+	case "", searchSubstring:
+	case searchFullText:
+		q.FullText = true
+	default:
+		return q, &paramError{Param: "mode", Value: mode, Message: "неизвестный режим поиска"}
+	}
+	// Нужен ли фрагмент текста с подсветкой найденных слов (только для полнотекстового поиска)
+	q.Highlight = values.Get("highlight") == "true"
+	// Читаем границы времени публикации
+	if q.From, err = parseTime(values.Get("from"), false); err != nil {
+		return q, &paramError{Param: "from", Value: values.Get("from"), Message: err.Error()}
+	}
+	if q.To, err = parseTime(values.Get("to"), true); err != nil {
+		return q, &paramError{Param: "to", Value: values.Get("to"), Message: err.Error()}
+	}
+	if q.From != 0 && q.To != 0 && q.From > q.To {
+		return q, &paramError{Param: "from", Value: values.Get("from"), Message: "параметр from больше параметра to"}
+	}
+	// Источник может быть задан адресом фида - тогда отбираются новости источника с этим адресом,
+	// или хостом - тогда новости, ссылки на которые ведут на этот хост
+	if source := strings.TrimSpace(values.Get("source")); source != "" {
+		if strings.Contains(source, "://") {
+			q.SourceURL = source
+		} else if q.Source = storage.Host(source); q.Source == "" {
+			return q, &paramError{Param: "source", Value: source, Message: "ожидается адрес фида или хост"}
+		}
+	}
+	// Читаем идентификатор источника
+	if sourceId := values.Get("source_id"); sourceId != "" {
+		if q.SourceId, err = strconv.Atoi(sourceId); err != nil || q.SourceId < 1 {
+			return q, &paramError{Param: "source_id", Value: sourceId, Message: "ожидается положительное целое число"}
+		}
+	}
+	// Группировать ли похожие новости из разных источников
+	switch collapse := values.Get("collapse"); collapse {
+	case "", "false":
+	case "true":
+		q.Collapse = true
+	default:
+		return q, &paramError{Param: "collapse", Value: collapse, Message: "ожидается true или false"}
+	}
+	// Читаем поле и направление сортировки
+	switch q.Sort = values.Get("sort"); q.Sort {
+	case "", storage.SortId, storage.SortPubTime:
+	case storage.SortRelevance:
+		if !q.FullText || q.Search == "" {
+			return q, &paramError{Param: "sort", Value: q.Sort, Message: "сортировка по релевантности доступна только для полнотекстового поиска"}
+		}
+	default:
+		return q, &paramError{Param: "sort", Value: q.Sort, Message: "неизвестное поле сортировки"}
+	}
+	switch order := values.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		q.Asc = true
+	default:
+		return q, &paramError{Param: "order", Value: order, Message: "неизвестное направление сортировки"}
+	}
+	return q, nil
+}
+
+// Разбирает время: unix время в секундах, RFC 3339 или дата в формате 2006-01-02 (UTC).
+// Для верхней границы дата означает конец дня. Пустая строка - без ограничения.
+func parseTime(s string, endOfDay bool) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unix, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return 0, fmt.Errorf("ожидается unix время, RFC 3339 или дата ГГГГ-ММ-ДД")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,29 @@
+package news
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNewsQuerySource(t *testing.T) {
+	tests := []struct {
+		source    string
+		host      string
+		sourceURL string
+	}{
+		// Адрес фида отбирает новости источника, хост - новости со ссылками на этот хост
+		{"https://feeds.example.org/all.xml", "", "https://feeds.example.org/all.xml"},
+		{" http://example.com/rss ", "", "http://example.com/rss"},
+		{"www.Example.com", "example.com", ""},
+		{"example.com/news", "example.com", ""},
+	}
+	for _, tt := range tests {
+		q, err := newsQuery(url.Values{"source": {tt.source}})
+		if err != nil || q.Source != tt.host || q.SourceURL != tt.sourceURL {
+			t.Errorf("source=%q: Source %q, SourceURL %q, %v, ожидалось %q, %q", tt.source, q.Source, q.SourceURL, err, tt.host, tt.sourceURL)
+		}
+	}
+	if _, err := newsQuery(url.Values{"source": {"/news"}}); err == nil {
+		t.Error("source без хоста не вернул ошибку")
+	}
+}
@@ -0,0 +1,214 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Тело запроса добавления и изменения источника, отсутствующие поля при изменении не меняются
+type sourceRequest struct {
+	URL          *string `json:"url"`           // Адрес фида
+	Title        *string `json:"title"`         // Название источника
+	Enabled      *bool   `json:"enabled"`       // Опрашивать ли источник, при добавлении по-умолчанию true
+	PollInterval *int    `json:"poll_interval"` // Период опроса в минутах, 0 - период ридера по-умолчанию
+	Category     *string `json:"category"`      // Категория источника, вложенные категории разделяются "/"
+}
+
+// Переносит заданные поля запроса в источник и проверяет их
+func (req sourceRequest) apply(src *storage.Source) error {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &paramError{Param: "url", Value: *req.URL, Message: "ожидается адрес http или https"}
+		}
+		src.URL = *req.URL
+	}
+	if req.Title != nil {
+		src.Title = *req.Title
+	}
+	if req.Enabled != nil {
+		src.Enabled = *req.Enabled
+	}
+	if req.PollInterval != nil {
+		if *req.PollInterval < 0 {
+			return &paramError{Param: "poll_interval", Value: strconv.Itoa(*req.PollInterval), Message: "ожидается неотрицательное число"}
+		}
+		src.PollInterval = *req.PollInterval
+	}
+	if req.Category != nil {
+		src.Category = *req.Category
+	}
+	return nil
+}
+
+// Отвечает клиенту кодом, соответствующим ошибке хранилища
+func writeSourceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, storage.ErrDuplicateSource):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Отвечает клиенту источником в виде json
+func writeSource(w http.ResponseWriter, status int, src storage.Source) {
+	bytes, err := json.Marshal(src)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
+
+// Обработчик получения списка источников новостей с состоянием их опроса
+func (n *newsService) sourcesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	sources, err := n.db.Sources(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sources == nil {
+		sources = []storage.Source{}
+	}
+	bytes, err := json.Marshal(sources)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+// Обработчик получения состояния опроса фидов, сохранен для совместимости: то же состояние возвращает /sources
+func (n *newsService) feedsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	sources, err := n.db.Sources(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	feeds := make([]storage.FeedState, 0, len(sources))
+	for _, src := range sources {
+		feeds = append(feeds, src.FeedState)
+	}
+	bytes, err := json.Marshal(feeds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+// Обработчик получения источника новостей
+func (n *newsService) sourceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	src, err := n.db.SourceByID(ctx, id)
+	if err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	writeSource(w, http.StatusOK, src)
+}
+
+// Обработчик добавления источника новостей
+func (n *newsService) addSourceHandler(w http.ResponseWriter, r *http.Request) {
+	var req sourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.URL == nil {
+		writeParamError(w, &paramError{Param: "url", Message: "не указан адрес источника"})
+		return
+	}
+	src := storage.Source{Enabled: true}
+	if err := req.apply(&src); err != nil {
+		writeParamError(w, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	id, err := n.db.AddSource(ctx, src)
+	if err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	src, err = n.db.SourceByID(ctx, id)
+	if err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	writeSource(w, http.StatusCreated, src)
+}
+
+// Обработчик изменения источника новостей, в том числе его отключения
+func (n *newsService) updateSourceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req sourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	src, err := n.db.SourceByID(ctx, id)
+	if err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	if err := req.apply(&src); err != nil {
+		writeParamError(w, err)
+		return
+	}
+	if err := n.db.UpdateSource(ctx, src); err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	src, err = n.db.SourceByID(ctx, id)
+	if err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	writeSource(w, http.StatusOK, src)
+}
+
+// Обработчик удаления источника новостей
+func (n *newsService) deleteSourceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), n.timeout)
+	defer cancel()
+	if err := n.db.DeleteSource(ctx, id); err != nil {
+		writeSourceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,12 +5,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Поддерживаемые типы хранилища
+const (
+	StoragePostgres = "postgres" // Хранилище PostgreSQL
+	StorageMemory   = "memory"   // Хранилище в оперативной памяти
+)
+
 // Структура конфигурации
 type Config struct {
+	storage           string
 	postgresUser      string
 	postgresPass      string
 	postgresDatabase  string
@@ -19,6 +27,9 @@ type Config struct {
 	commentsAddress   string
 	censorAddress     string
 	newsPerPage       int
+	newsPerPageMin    int
+	newsPerPageMax    int
+	requestTimeout    time.Duration
 	rssConfig         []byte
 }
 
@@ -27,17 +38,29 @@ func NewConfig() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		return &Config{}, err
 	}
-	postgresUser, exist := os.LookupEnv("POSTGRES_USERNAME")
+	// Тип хранилища, по-умолчанию PostgreSQL
+	storage, exist := os.LookupEnv("STORAGE")
 	if !exist {
-		return &Config{}, errors.New("POSTGRES_USERNAME not found")
+		storage = StoragePostgres
 	}
-	postgresPass, exist := os.LookupEnv("POSTGRES_PASSWORD")
-	if !exist {
-		return &Config{}, errors.New("POSTGRES_PASSWORD not found")
+	if storage != StoragePostgres && storage != StorageMemory {
+		return &Config{}, fmt.Errorf("STORAGE unknown type: %s", storage)
 	}
-	postgresDatabase, exist := os.LookupEnv("POSTGRES_DATABASE")
-	if !exist {
-		return &Config{}, errors.New("POSTGRES_DATABASE not found")
+	// Параметры подключения к PostgreSQL нужны только для соответствующего хранилища
+	var postgresUser, postgresPass, postgresDatabase string
+	if storage == StoragePostgres {
+		postgresUser, exist = os.LookupEnv("POSTGRES_USERNAME")
+		if !exist {
+			return &Config{}, errors.New("POSTGRES_USERNAME not found")
+		}
+		postgresPass, exist = os.LookupEnv("POSTGRES_PASSWORD")
+		if !exist {
+			return &Config{}, errors.New("POSTGRES_PASSWORD not found")
+		}
+		postgresDatabase, exist = os.LookupEnv("POSTGRES_DATABASE")
+		if !exist {
+			return &Config{}, errors.New("POSTGRES_DATABASE not found")
+		}
 	}
 	apiGatewayAddress, exist := os.LookupEnv("APIGATEWAY_ADDRESS")
 	if !exist {
@@ -67,6 +90,24 @@ func NewConfig() (*Config, error) {
 	if newsPerPage < 1 {
 		return &Config{}, fmt.Errorf("NEWS_PER_PAGE need to bo over 1")
 	}
+	// Границы размера страницы, который может запросить клиент
+	newsPerPageMin, err := lookupInt("NEWS_PER_PAGE_MIN", 1)
+	if err != nil {
+		return &Config{}, err
+	}
+	newsPerPageMax, err := lookupInt("NEWS_PER_PAGE_MAX", 100)
+	if err != nil {
+		return &Config{}, err
+	}
+	if newsPerPage < newsPerPageMin || newsPerPage > newsPerPageMax {
+		return &Config{}, fmt.Errorf("NEWS_PER_PAGE need to be between NEWS_PER_PAGE_MIN and NEWS_PER_PAGE_MAX")
+	}
+	// Предельное время обработки запроса к хранилищу в секундах, по-умолчанию 5
+	requestTimeoutSeconds, err := lookupInt("REQUEST_TIMEOUT", 5)
+	if err != nil {
+		return &Config{}, err
+	}
+	requestTimeout := time.Duration(requestTimeoutSeconds) * time.Second
 	rssConfigFile, exist := os.LookupEnv("RSS_CONFIG")
 	if !exist {
 		return &Config{}, errors.New("RSS_CONFIG not found")
@@ -77,6 +118,7 @@ func NewConfig() (*Config, error) {
 		return &Config{}, fmt.Errorf("error while reading rss configuration file: %s", err.Error())
 	}
 	return &Config{
+		storage,
 		postgresUser,
 		postgresPass,
 		postgresDatabase,
@@ -85,10 +127,33 @@ func NewConfig() (*Config, error) {
 		commentsAddress,
 		censorAddress,
 		newsPerPage,
+		newsPerPageMin,
+		newsPerPageMax,
+		requestTimeout,
 		rssConfig,
 	}, nil
 }
 
+// Читает необязательный положительный целочисленный параметр, при отсутствии возвращает значение по-умолчанию
+func lookupInt(name string, def int) (int, error) {
+	str, exist := os.LookupEnv(name)
+	if !exist {
+		return def, nil
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("%s conversion error: %s", name, err.Error())
+	}
+	if value < 1 {
+		return 0, fmt.Errorf("%s need to be at least 1", name)
+	}
+	return value, nil
+}
+
+func (c *Config) Storage() string {
+	return c.storage
+}
+
 func (c *Config) ConString() string {
 	return fmt.Sprintf("postgres://%s:%s@localhost:5432/%s?sslmode=disable", c.postgresUser, c.postgresPass, c.postgresDatabase)
 }
@@ -113,6 +178,18 @@ func (c *Config) NewsPerPage() int {
 	return c.newsPerPage
 }
 
+func (c *Config) NewsPerPageMin() int {
+	return c.newsPerPageMin
+}
+
+func (c *Config) NewsPerPageMax() int {
+	return c.newsPerPageMax
+}
+
+func (c *Config) RequestTimeout() time.Duration {
+	return c.requestTimeout
+}
+
 func (c *Config) RssConfig() []byte {
 	return c.rssConfig
 }
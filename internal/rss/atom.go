@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"encoding/xml"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Набор вложенных структур для раскодировки xml фида Atom 1.0
+type atomFeed struct {
+	Title   atomText    `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// Вложения Media RSS объявлены первыми, чтобы media:content не попадал в поле Content
+type atomEntry struct {
+	mediaRSS
+	Title     atomText   `xml:"title"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Текстовая конструкция Atom: text, html (экранированный html) или xhtml (вложенная разметка)
+type atomText struct {
+	Type string
+	Body string
+}
+
+// Раскодирует текстовую конструкцию с учетом атрибута type
+func (t *atomText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			t.Type = attr.Value
+		}
+	}
+	// Разметку xhtml забираем как есть, html тэги удаляются позже вместе с остальными
+	if t.Type == "xhtml" {
+		var v struct {
+			Inner string `xml:",innerxml"`
+		}
+		err := d.DecodeElement(&v, &start)
+		t.Body = v.Inner
+		return err
+	}
+	var v struct {
+		Text string `xml:",chardata"`
+	}
+	err := d.DecodeElement(&v, &start)
+	t.Body = v.Text
+	return err
+}
+
+// Возвращает ссылку на страницу: rel="alternate" (или без rel), предпочтительно text/html
+func alternateLink(links []atomLink) string {
+	var link string
+	for _, l := range links {
+		if l.Rel != "" && l.Rel != "alternate" {
+			continue
+		}
+		if l.Type == "" || l.Type == "text/html" {
+			return l.Href
+		}
+		if link == "" {
+			link = l.Href
+		}
+	}
+	return link
+}
+
+// Метод разбирает адрес сайта и записи из фида Atom 1.0
+func parseAtom(b []byte) (string, []entry, error) {
+	var feed atomFeed
+	// Раскодируем xml в структуру
+	err := xml.Unmarshal(b, &feed)
+	if err != nil {
+		return "", []entry{}, err
+	}
+
+	var entries []entry
+	// Итерируем по массиву записей
+	for _, item := range feed.Entries {
+		var e entry
+		e.Title = htmlText(item.Title.Body)
+		e.Summary = item.Summary.Body
+		e.Content = item.Content.Body
+		e.Link = alternateLink(item.Links)
+		// Время публикации, если его нет - время последнего изменения
+		pubTime := item.Published
+		if pubTime == "" {
+			pubTime = item.Updated
+		}
+		e.PubTime, _ = parseDate(pubTime)
+		for _, l := range item.Links {
+			if l.Rel == "enclosure" {
+				e.Media = addMedia(e.Media, storage.Media{URL: l.Href, Type: l.Type})
+			}
+		}
+		e.Media = addMedia(e.Media, item.media()...)
+		entries = append(entries, e)
+	}
+	return alternateLink(feed.Links), entries, nil
+}
@@ -0,0 +1,36 @@
+package rss
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Объявление кодировки в начале xml документа: <?xml version="1.0" encoding="windows-1251"?>
+var xmlEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*["'])([A-Za-z0-9._:-]+)(["'])`)
+
+// Перекодирует документ в UTF-8. Кодировка определяется по метке порядка байтов, затем по параметру
+// charset заголовка Content-Type, затем по объявлению xml, по-умолчанию UTF-8. Объявление кодировки
+// в перекодированном документе заменяется на UTF-8, чтобы его принял разборщик xml.
+func toUTF8(contentType string, b []byte) ([]byte, error) {
+	name := "utf-8"
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		name = params["charset"]
+	} else if m := xmlEncoding.FindSubmatch(b); m != nil {
+		name = string(m[2])
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестная кодировка документа: %s", name)
+	}
+	// Метка порядка байтов UTF-8 или UTF-16 важнее заявленной кодировки
+	b, _, err = transform.Bytes(unicode.BOMOverride(enc.NewDecoder()), b)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка перекодировки документа из %s: %w", name, err)
+	}
+	return xmlEncoding.ReplaceAll(b, []byte("${1}UTF-8${3}")), nil
+}
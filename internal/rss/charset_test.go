@@ -0,0 +1,77 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Фид с кириллицей, %s - объявление xml
+const cyrillicFeed = `%s<rss version="2.0"><channel><link>https://example.ru/</link>
+<item><title>Новости: съёмка "Ёжика" в тумане</title><description>Текст новости</description><link>https://example.ru/1</link></item>
+</channel></rss>`
+
+// Собирает фид с объявлением xml и кодирует его
+func encodeFeed(t *testing.T, declaration string, enc encoding.Encoding) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(strings.Replace(cyrillicFeed, "%s", declaration, 1)))
+	if err != nil {
+		t.Fatalf("ошибка кодирования фида: %v", err)
+	}
+	return b
+}
+
+func TestToUTF8(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	utf16be := unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	utf8bom := unicode.UTF8BOM
+	tests := []struct {
+		name        string
+		contentType string
+		doc         []byte
+	}{
+		{"windows-1251 в объявлении xml", "text/xml", encodeFeed(t, `<?xml version="1.0" encoding="windows-1251"?>`, charmap.Windows1251)},
+		{"koi8-r в объявлении xml", "application/rss+xml", encodeFeed(t, `<?xml version='1.0' encoding='KOI8-R'?>`, charmap.KOI8R)},
+		{"cp1251 в объявлении xml", "", encodeFeed(t, `<?xml version="1.0" encoding = "cp1251" ?>`, charmap.Windows1251)},
+		{"windows-1251 в Content-Type", "application/rss+xml; charset=windows-1251", encodeFeed(t, `<?xml version="1.0"?>`, charmap.Windows1251)},
+		// Заголовок важнее объявления в документе
+		{"koi8-r в Content-Type, utf-8 в объявлении", `text/xml; charset="koi8-r"`, encodeFeed(t, `<?xml version="1.0" encoding="utf-8"?>`, charmap.KOI8R)},
+		{"без объявления", "", []byte(strings.Replace(cyrillicFeed, "%s", "", 1))},
+		// Метка порядка байтов важнее заголовка и объявления
+		{"UTF-16LE с BOM", "text/xml", encodeFeed(t, `<?xml version="1.0" encoding="utf-16"?>`, utf16le)},
+		{"UTF-16BE с BOM и windows-1251 в Content-Type", "text/xml; charset=windows-1251", encodeFeed(t, `<?xml version="1.0" encoding="windows-1251"?>`, utf16be)},
+		{"UTF-8 с BOM", "", encodeFeed(t, `<?xml version="1.0" encoding="utf-8"?>`, utf8bom)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := toUTF8(tt.contentType, tt.doc)
+			if err != nil {
+				t.Fatalf("toUTF8: %v", err)
+			}
+			if !strings.Contains(string(b), `съёмка "Ёжика"`) {
+				t.Errorf("toUTF8 = %q", b)
+			}
+			// Перекодированный документ разбирается целиком
+			_, news, err := parse(tt.contentType, tt.doc, time.Now())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if len(news) != 1 || news[0].Title != `Новости: съёмка "Ёжика" в тумане` {
+				t.Errorf("parse = %+v", news)
+			}
+		})
+	}
+}
+
+func TestToUTF8Unknown(t *testing.T) {
+	if _, err := toUTF8("text/xml; charset=x-unknown", []byte("<rss/>")); err == nil {
+		t.Error("toUTF8 с неизвестной кодировкой в Content-Type не вернул ошибку")
+	}
+	if _, err := toUTF8("", []byte(`<?xml version="1.0" encoding="x-unknown"?><rss/>`)); err == nil {
+		t.Error("toUTF8 с неизвестной кодировкой в объявлении не вернул ошибку")
+	}
+}
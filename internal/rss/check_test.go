@@ -0,0 +1,48 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckFeed(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "rss2.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		// Тип содержимого не указывает на фид, формат определяется по документу
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(feed)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<!DOCTYPE html><html><body>Не фид</body></html>"))
+	})
+	server := httptest.NewServer(mux)
+	closed := httptest.NewServer(mux)
+	closed.Close()
+	defer server.Close()
+
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{server.URL + "/feed", nil},
+		{server.URL + "/page", ErrUnknownFormat},
+		{server.URL + "/missing", ErrUnreachable},
+		{closed.URL + "/feed", ErrUnreachable},
+	}
+	for _, tt := range tests {
+		err := CheckFeed(context.Background(), tt.url)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CheckFeed(%s) = %v, ожидалось %v", tt.url, err, tt.want)
+		}
+	}
+}
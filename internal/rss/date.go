@@ -0,0 +1,159 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Смещения буквенных часовых поясов, которые встречаются в фидах. Неизвестные сокращения Go разбирает
+// с нулевым смещением, поэтому до разбора пояс заменяется числовым смещением.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"MSD":  "+0400",
+	"IST":  "+0530",
+	"JST":  "+0900",
+}
+
+// Форматы дат после приведения строки: без дня недели, с числовым смещением вместо буквенного пояса
+var dateLayouts = []string{
+	// RFC 822 / RFC 1123 и их вариации
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	// RFC 850 и формат cookie Netscape: день недели полностью, дата через дефис
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	// Формат date и Ruby
+	"Jan 2 15:04:05 -0700 2006",
+	// ISO 8601 и RFC 3339, дробная часть секунд необязательна
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Числовое смещение с двоеточием, отделенное пробелом: +03:00
+var colonOffset = regexp.MustCompile(`^[+-]\d\d:\d\d$`)
+
+// Разбирает дату публикации в одном из распространенных в фидах форматов. Строка без часового
+// пояса считается временем UTC.
+func parseDate(s string) (time.Time, error) {
+	value := normalizeDate(s)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("не указана дата")
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неизвестный формат даты: %q", s)
+}
+
+// Приводит строку даты к виду dateLayouts: убирает лишние пробелы и день недели,
+// заменяет буквенный часовой пояс числовым смещением
+func normalizeDate(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	// День недели: "Mon," на любом языке или "Mon" без запятой
+	if first := fields[0]; strings.HasSuffix(first, ",") && isLetters(strings.TrimSuffix(first, ",")) {
+		fields = fields[1:]
+	} else if isWeekday(first) {
+		fields = fields[1:]
+	}
+	// Дата без пробела после запятой: "Mon,02 Jan ..."
+	if len(fields) > 0 {
+		if i := strings.Index(fields[0], ","); i > 0 && isLetters(fields[0][:i]) {
+			fields[0] = fields[0][i+1:]
+		}
+	}
+	// Комментарий в скобках в конце строки: "+0300 (MSK)". Он заменяет часовой пояс, только если пояс не указан
+	for i := len(fields) - 1; i > 0 && strings.HasSuffix(fields[len(fields)-1], ")"); i-- {
+		if !strings.HasPrefix(fields[i], "(") {
+			continue
+		}
+		comment := strings.Trim(strings.Join(fields[i:], " "), "()")
+		fields = fields[:i]
+		if !isZone(fields[i-1]) && isLetters(comment) {
+			fields = append(fields, comment)
+		}
+		break
+	}
+	for i, f := range fields {
+		switch {
+		case zoneOffsets[strings.ToUpper(f)] != "":
+			fields[i] = zoneOffsets[strings.ToUpper(f)]
+		case colonOffset.MatchString(f):
+			fields[i] = strings.Replace(f, ":", "", 1)
+		case i == len(fields)-1 && i > 0 && isLetters(f) && strings.ToUpper(f) == f:
+			// Неизвестный буквенный пояс в конце строки считаем UTC
+			fields[i] = "+0000"
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// Проверяет, что поле строки даты - часовой пояс: числовое смещение или буквенное сокращение
+func isZone(s string) bool {
+	return strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") || isLetters(s)
+}
+
+// Проверяет, что строка непустая и состоит только из букв
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Проверяет, что строка - английское название дня недели, полное или сокращенное
+func isWeekday(s string) bool {
+	if len(s) < 3 {
+		return false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := day.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	msk := time.FixedZone("", 3*3600)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		// RFC 822 / RFC 1123
+		{"Tue, 05 Mar 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 2024 07:00:00 GMT", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"Tue, 5 Mar 2024 10:00:00 MSK", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 2024 02:00:00 EST", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"Tue, 05 Mar 2024 07:00:00 Z", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"Tue, 05 Mar 2024 10:00:00 +03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue,05 Mar 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"  Tue,  05 Mar   2024 10:00:00 +0300 ", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Вт, 05 Mar 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tuesday 05 Mar 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"05 Mar 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 2024 10:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 24 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 March 2024 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 2024 07:00:00", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"05 Mar 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		// Неизвестный буквенный пояс считается UTC
+		{"Tue, 05 Mar 2024 07:00:00 XYZ", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		// Комментарий в скобках после пояса не учитывается, без пояса - заменяет его
+		{"05 Mar 2024 10:00:00 +0300 (MSK)", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"Tue, 05 Mar 2024 07:00:00 +0000 (Coordinated Universal Time)", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"Tue, 05 Mar 2024 10:00:00 (MSK)", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		// RFC 850
+		{"Tuesday, 05-Mar-24 10:00:00 GMT", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"Tue, 05-Mar-2024 10:00:00 GMT", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		// Формат date
+		{"Tue Mar 5 10:00:00 +0300 2024", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		// ISO 8601 / RFC 3339
+		{"2024-03-05T10:00:00+03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"2024-03-05T07:00:00Z", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"2024-03-05T07:00:00.123456Z", time.Date(2024, 3, 5, 7, 0, 0, 123456000, time.UTC)},
+		{"2024-03-05T10:00:00+0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"2024-03-05T10:00+03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"2024-03-05T07:00:00", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"2024-03-05T07:00", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"2024-03-05 10:00:00+03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"2024-03-05 10:00:00 +0300", time.Date(2024, 3, 5, 10, 0, 0, 0, msk)},
+		{"2024-03-05 07:00:00", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"2024-03-05 07:00", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.value)
+		if err != nil {
+			t.Errorf("parseDate(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, ожидалось %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"   ",
+		"вчера",
+		"05.03.2024",
+		"Tue, 32 Mar 2024 10:00:00 +0300",
+		"2024-13-05T10:00:00Z",
+		"1709622000",
+	} {
+		if got, err := parseDate(value); err == nil {
+			t.Errorf("parseDate(%q) = %v, ожидалась ошибка", value, got)
+		}
+	}
+}
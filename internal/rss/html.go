@@ -0,0 +1,253 @@
+package rss
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Наибольшая длина краткого содержания новости в символах
+const summaryLength = 500
+
+// Элементы, содержимое которых не является текстом новости и пропускается целиком
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Svg:      true,
+	atom.Head:     true,
+}
+
+// Блочные элементы, начало и конец которых разделяют абзацы текста
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Br:         true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Li:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Hr:         true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+}
+
+// Элементы, которые остаются в очищенном html, и их допустимые атрибуты
+var allowedElements = map[atom.Atom][]string{
+	atom.P:          nil,
+	atom.Br:         nil,
+	atom.B:          nil,
+	atom.Strong:     nil,
+	atom.I:          nil,
+	atom.Em:         nil,
+	atom.U:          nil,
+	atom.S:          nil,
+	atom.Ul:         nil,
+	atom.Ol:         nil,
+	atom.Li:         nil,
+	atom.Blockquote: nil,
+	atom.Pre:        nil,
+	atom.Code:       nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.A:          {"href", "title"},
+	atom.Img:        {"src", "alt", "title"},
+}
+
+// Элементы без закрывающего тэга
+var voidElements = map[atom.Atom]bool{
+	atom.Br:  true,
+	atom.Hr:  true,
+	atom.Img: true,
+}
+
+// Разбирает html текст новости. Возвращает абзацы простого текста, в которых html сущности раскодированы,
+// а пробелы схлопнуты, очищенный html, в котором оставлены только разрешенные элементы и атрибуты,
+// и изображения из очищенного html. Содержимое скриптов, стилей и подобных элементов отбрасывается.
+func extractHTML(s string) (paragraphs []string, body string, images []storage.Media) {
+	var (
+		z    = html.NewTokenizer(strings.NewReader(s))
+		text strings.Builder // Текущий абзац
+		out  strings.Builder // Очищенный html
+		open []atom.Atom     // Открытые разрешенные элементы
+		skip int             // Глубина вложенности пропускаемых элементов
+	)
+	flush := func() {
+		if p := strings.Join(strings.Fields(text.String()), " "); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		text.Reset()
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// Конец текста, закрываем незакрытые элементы
+			flush()
+			for i := len(open) - 1; i >= 0; i-- {
+				out.WriteString("</" + open[i].String() + ">")
+			}
+			return paragraphs, strings.TrimSpace(out.String()), images
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			t := string(z.Text())
+			text.WriteString(t)
+			out.WriteString(html.EscapeString(t))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if skippedElements[tok.DataAtom] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			if blockElements[tok.DataAtom] {
+				flush()
+			}
+			if attrs, ok := allowedElements[tok.DataAtom]; ok {
+				if tag := startTag(tok, attrs); tag != "" {
+					out.WriteString(tag)
+					if tok.DataAtom == atom.Img {
+						images = addMedia(images, image(tok))
+					}
+					if !voidElements[tok.DataAtom] {
+						open = append(open, tok.DataAtom)
+					}
+				}
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			if skippedElements[tok.DataAtom] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			if blockElements[tok.DataAtom] {
+				flush()
+			}
+			// Закрываем элемент и все незакрытые элементы внутри него, лишние закрывающие тэги отбрасываем
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tok.DataAtom {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					out.WriteString("</" + open[j].String() + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+}
+
+// Возвращает открывающий тэг с допустимыми атрибутами. Ссылки допускаются только http и https,
+// изображение без ссылки отбрасывается.
+func startTag(tok html.Token, allowed []string) string {
+	var (
+		b      strings.Builder
+		hasSrc bool
+	)
+	b.WriteString("<" + tok.DataAtom.String())
+	for _, a := range tok.Attr {
+		if a.Namespace != "" || !slices.Contains(allowed, a.Key) {
+			continue
+		}
+		if a.Key == "href" || a.Key == "src" {
+			if !safeURL(a.Val) {
+				continue
+			}
+			hasSrc = hasSrc || a.Key == "src"
+		}
+		b.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+	}
+	switch tok.DataAtom {
+	case atom.A:
+		b.WriteString(` rel="nofollow noopener"`)
+	case atom.Img:
+		if !hasSrc {
+			return ""
+		}
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// Возвращает изображение из тэга img с размерами из атрибутов width и height
+func image(tok html.Token) storage.Media {
+	m := storage.Media{Type: "image"}
+	for _, a := range tok.Attr {
+		switch a.Key {
+		case "src":
+			m.URL = a.Val
+		case "width":
+			m.Width = dimension(a.Val)
+		case "height":
+			m.Height = dimension(a.Val)
+		}
+	}
+	return m
+}
+
+// Проверяет, что ссылка абсолютная и ведет по http или https
+func safeURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Возвращает простой текст из html одной строкой
+func htmlText(s string) string {
+	paragraphs, _, _ := extractHTML(s)
+	return strings.Join(paragraphs, " ")
+}
+
+// Возвращает краткое содержание новости: первый абзац, обрезанный по границе слова до summaryLength символов
+func summary(paragraphs []string) string {
+	if len(paragraphs) == 0 {
+		return ""
+	}
+	p := paragraphs[0]
+	if utf8.RuneCountInString(p) <= summaryLength {
+		return p
+	}
+	runes := []rune(p)[:summaryLength]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
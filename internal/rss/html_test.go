@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+func TestExtractHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		paragraphs []string
+		body       string
+	}{
+		{
+			name:       "сущности",
+			in:         "<p>AT&amp;T &laquo;Связь&raquo; &#8212; 5 &lt; 6&nbsp;шт.</p>",
+			paragraphs: []string{"AT&T «Связь» — 5 < 6 шт."},
+			body:       "<p>AT&amp;T «Связь» — 5 &lt; 6 шт.</p>",
+		},
+		{
+			name:       "скрипты и стили",
+			in:         `<p>До</p><script>alert("x")</script><style>p {color: red}</style><noscript>без js</noscript><p>После</p>`,
+			paragraphs: []string{"До", "После"},
+			body:       "<p>До</p><p>После</p>",
+		},
+		{
+			name:       "тэги через несколько строк",
+			in:         "<p\n  class=\"lead\">Первая\n   строка</p><a\nhref=\"https://example.com/\"\nonclick=\"x()\">ссылка</a>",
+			paragraphs: []string{"Первая строка", "ссылка"},
+			body:       "<p>Первая\n   строка</p><a href=\"https://example.com/\" rel=\"nofollow noopener\">ссылка</a>",
+		},
+		{
+			name:       "запрещенные элементы, атрибуты и ссылки",
+			in:         `<div><iframe src="https://example.com/"></iframe><a href="javascript:alert(1)">опасная</a> <b onmouseover="x()">жирный</b></div>`,
+			paragraphs: []string{"опасная жирный"},
+			body:       "<a rel=\"nofollow noopener\">опасная</a> <b>жирный</b>",
+		},
+		{
+			name:       "незакрытые элементы",
+			in:         "<p><b>текст",
+			paragraphs: []string{"текст"},
+			body:       "<p><b>текст</b></p>",
+		},
+	}
+	for _, tt := range tests {
+		paragraphs, body, _ := extractHTML(tt.in)
+		if !slices.Equal(paragraphs, tt.paragraphs) {
+			t.Errorf("%s: абзацы %q, ожидалось %q", tt.name, paragraphs, tt.paragraphs)
+		}
+		if body != tt.body {
+			t.Errorf("%s: html %q, ожидалось %q", tt.name, body, tt.body)
+		}
+	}
+}
+
+func TestExtractHTMLImages(t *testing.T) {
+	_, _, images := extractHTML(`<p><img src="https://example.com/1.jpg" width="640" height="480"><img src="data:image/png;base64,AA"><img></p>`)
+	want := []storage.Media{{URL: "https://example.com/1.jpg", Type: "image", Width: 640, Height: 480}}
+	if !slices.Equal(images, want) {
+		t.Errorf("изображения %+v, ожидалось %+v", images, want)
+	}
+}
+
+func TestSummaryLength(t *testing.T) {
+	short := "Короткий абзац."
+	if got := summary([]string{short, "второй"}); got != short {
+		t.Errorf("summary = %q, ожидалось %q", got, short)
+	}
+	// Длинный абзац обрезается по границе слова не длиннее summaryLength и получает многоточие
+	long := strings.Repeat("слово, ", summaryLength)
+	got := summary([]string{long})
+	if !strings.HasSuffix(got, "слово…") || utf8.RuneCountInString(got) > summaryLength+1 {
+		t.Errorf("summary длинного абзаца: %d символов, %q", utf8.RuneCountInString(got), got[len(got)-20:])
+	}
+	if summary(nil) != "" {
+		t.Error("summary без абзацев не пустой")
+	}
+}
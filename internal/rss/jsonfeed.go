@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"strings"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Набор вложенных структур для раскодировки JSON Feed 1.0/1.1
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	HomePageURL string         `json:"home_page_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	Title         string `json:"title"`
+	Summary       string `json:"summary"`
+	ContentHTML   string `json:"content_html"`
+	ContentText   string `json:"content_text"`
+	URL           string `json:"url"`
+	ExternalURL   string `json:"external_url"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+	Image         string `json:"image"`
+	BannerImage   string `json:"banner_image"`
+	Attachments   []struct {
+		URL      string `json:"url"`
+		MIMEType string `json:"mime_type"`
+	} `json:"attachments"`
+}
+
+// Проверяет, что документ - JSON Feed: объект с версией https://jsonfeed.org/version/...
+func isJSONFeed(b []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return false
+	}
+	var feed struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(b, &feed); err != nil {
+		return false
+	}
+	return strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/")
+}
+
+// Метод разбирает адрес сайта и записи из JSON Feed
+func parseJSONFeed(b []byte) (string, []entry, error) {
+	var feed jsonFeed
+	// Раскодируем json в структуру
+	err := json.Unmarshal(b, &feed)
+	if err != nil {
+		return "", []entry{}, err
+	}
+
+	var entries []entry
+	// Итерируем по массиву записей
+	for _, item := range feed.Items {
+		var e entry
+		e.Title = item.Title
+		// Полный текст в html предпочтительнее простого текста. Краткое содержание и content_text - простой текст,
+		// он экранируется, чтобы угловые скобки и амперсанды в нем не разбирались как разметка
+		e.Summary = html.EscapeString(item.Summary)
+		e.Content = firstNonEmpty(item.ContentHTML, html.EscapeString(item.ContentText))
+		e.Link = firstNonEmpty(item.URL, item.ExternalURL)
+		// Время публикации, если его нет - время последнего изменения
+		e.PubTime, _ = parseDate(firstNonEmpty(item.DatePublished, item.DateModified))
+		e.Media = addMedia(e.Media,
+			storage.Media{URL: item.Image, Type: "image"},
+			storage.Media{URL: item.BannerImage, Type: "image"},
+		)
+		for _, a := range item.Attachments {
+			e.Media = addMedia(e.Media, storage.Media{URL: a.URL, Type: a.MIMEType})
+		}
+		entries = append(entries, e)
+	}
+	return feed.HomePageURL, entries, nil
+}
+
+// Возвращает первую непустую строку
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
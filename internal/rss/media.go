@@ -0,0 +1,94 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Вложения записи по спецификации Media RSS, встречаются в RSS 2.0 и Atom.
+// Структура встраивается в запись фида, media:group может содержать те же элементы.
+type mediaRSS struct {
+	Contents   []mediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []mediaContent `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Groups     []mediaRSS     `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// Элемент media:content или media:thumbnail. Размеры разбираются отдельно, чтобы
+// неверное значение атрибута не ломало разбор всего фида.
+type mediaContent struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"` // image, video, audio и др.
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+// Вложение записи RSS 2.0: <enclosure url="..." type="..." length="..."/>
+type enclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Возвращает вложения Media RSS, миниатюры считаются изображениями
+func (m mediaRSS) media() []storage.Media {
+	var media []storage.Media
+	for _, c := range m.Contents {
+		media = addMedia(media, storage.Media{
+			URL:    c.URL,
+			Type:   firstNonEmpty(c.Type, c.Medium),
+			Width:  dimension(c.Width),
+			Height: dimension(c.Height),
+		})
+	}
+	for _, c := range m.Thumbnails {
+		media = addMedia(media, storage.Media{
+			URL:    c.URL,
+			Type:   "image",
+			Width:  dimension(c.Width),
+			Height: dimension(c.Height),
+		})
+	}
+	for _, g := range m.Groups {
+		media = addMedia(media, g.media()...)
+	}
+	return media
+}
+
+// Добавляет вложения в список. Вложения без http(s) ссылки отбрасываются, повторная ссылка
+// не добавляется, а только дополняет уже добавленное вложение неизвестными ранее сведениями.
+func addMedia(media []storage.Media, add ...storage.Media) []storage.Media {
+Next:
+	for _, m := range add {
+		m.URL = strings.TrimSpace(m.URL)
+		if !safeURL(m.URL) {
+			continue
+		}
+		m.Type = strings.ToLower(strings.TrimSpace(m.Type))
+		for i := range media {
+			if media[i].URL != m.URL {
+				continue
+			}
+			// Вид файла уточняется MIME типом
+			if media[i].Type == "" || !strings.Contains(media[i].Type, "/") && strings.Contains(m.Type, "/") {
+				media[i].Type = m.Type
+			}
+			if media[i].Width == 0 && media[i].Height == 0 {
+				media[i].Width, media[i].Height = m.Width, m.Height
+			}
+			continue Next
+		}
+		media = append(media, m)
+	}
+	return media
+}
+
+// Разбирает размер в пикселях, неизвестный или неверный размер - 0
+func dimension(s string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "px"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Запись фида, приведенная к общему виду независимо от формата
+type entry struct {
+	Title   string          // Заголовок, простой текст
+	Summary string          // Краткое содержание записи, может содержать html
+	Content string          // Полный текст записи, может содержать html
+	Link    string          // Ссылка на страницу записи
+	Aliases []string        // Другие ссылки на запись, по которым она могла быть сохранена раньше
+	PubTime time.Time       // Время публикации, пустое - если в фиде его нет или его не удалось разобрать
+	Media   []storage.Media // Вложения записи, изображения из текста добавляются при приведении к новости
+}
+
+// Разборщик фида одного формата
+type parser struct {
+	name         string                                // Название формата для сообщений
+	contentTypes []string                              // MIME типы, которыми источники отдают фид этого формата
+	sniff        func([]byte) bool                     // Определяет формат по содержимому документа
+	parse        func([]byte) (string, []entry, error) // Разбирает адрес сайта и записи из документа
+}
+
+// Реестр поддерживаемых форматов фидов, чтобы добавить формат, достаточно добавить сюда его разборщик
+var parsers = []parser{
+	{
+		name:         "RSS 2.0",
+		contentTypes: []string{"application/rss+xml"},
+		sniff:        func(b []byte) bool { return rootElement(b) == "rss" },
+		parse:        parseRSS,
+	},
+	{
+		name:         "Atom 1.0",
+		contentTypes: []string{"application/atom+xml"},
+		sniff:        func(b []byte) bool { return rootElement(b) == "feed" },
+		parse:        parseAtom,
+	},
+	{
+		name:         "RSS 1.0 (RDF)",
+		contentTypes: []string{"application/rdf+xml"},
+		sniff:        func(b []byte) bool { return rootElement(b) == "RDF" },
+		parse:        parseRDF,
+	},
+	{
+		name:         "JSON Feed",
+		contentTypes: []string{"application/feed+json", "application/json"},
+		sniff:        isJSONFeed,
+		parse:        parseJSONFeed,
+	},
+}
+
+// Выбирает разборщик по типу содержимого и содержимому документа и разбирает новости из фида.
+// Тип содержимого из заголовка ответа только уточняет выбор: многие источники отдают фиды
+// как text/xml или text/html, поэтому формат всегда подтверждается по документу. Кроме новостей
+// возвращает адрес сайта, указанный в фиде. Записи без времени публикации получают время fetched.
+func parse(contentType string, b []byte, fetched time.Time) (string, []storage.NewsShortDetailed, error) {
+	// Разборщики работают только с UTF-8
+	b, err := toUTF8(contentType, b)
+	if err != nil {
+		return "", []storage.NewsShortDetailed{}, err
+	}
+	p, err := detect(contentType, b)
+	if err != nil {
+		return "", []storage.NewsShortDetailed{}, err
+	}
+	site, entries, err := p.parse(b)
+	if err != nil {
+		return "", []storage.NewsShortDetailed{}, fmt.Errorf("ошибка разбора фида %s: %w", p.name, err)
+	}
+	news := make([]storage.NewsShortDetailed, 0, len(entries))
+	for _, e := range entries {
+		// Дата в фиде не указана или ее не удалось разобрать - новость получает время опроса
+		if e.PubTime.IsZero() {
+			e.PubTime = fetched
+		}
+		news = append(news, e.news())
+	}
+	return strings.TrimSpace(site), news, nil
+}
+
+// Определяет формат фида
+func detect(contentType string, b []byte) (parser, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	// Сначала форматы, заявленные типом содержимого
+	for _, p := range parsers {
+		for _, t := range p.contentTypes {
+			if t == mediaType && p.sniff(b) {
+				return p, nil
+			}
+		}
+	}
+	// Затем все остальные
+	for _, p := range parsers {
+		if p.sniff(b) {
+			return p, nil
+		}
+	}
+	return parser{}, fmt.Errorf("%w (Content-Type: %s)", ErrUnknownFormat, contentType)
+}
+
+// Приводит запись к новости для сохранения в БД
+func (e entry) news() storage.NewsShortDetailed {
+	// Если в записи только краткое содержание или только полный текст, из него берется и то, и другое
+	paragraphs, body, images := extractHTML(firstNonEmpty(e.Content, e.Summary))
+	if e.Content != "" && e.Summary != "" {
+		var summaryImages []storage.Media
+		paragraphs, _, summaryImages = extractHTML(e.Summary)
+		images = addMedia(images, summaryImages...)
+	}
+	// Краткое содержание - первый абзац простого текста, полный текст сохраняем очищенным html
+	content := summary(paragraphs)
+	return storage.NewsShortDetailed{
+		Title:   e.Title,
+		Content: content,
+		Body:    body,
+		Link:    e.Link,
+		Aliases: e.Aliases,
+		PubTime: e.PubTime.Unix(),
+		// Сначала вложения из разметки фида, в них чаще указаны тип и размеры
+		Media: addMedia(e.Media, images...),
+		// Похожие новости ищутся по заголовку и краткому содержанию: полный текст в разных фидах бывает разной длины
+		Fingerprint: simhash(e.Title, content),
+	}
+}
+
+// Возвращает имя корневого элемента xml документа без пространства имен или пустую строку, если это не xml
+func rootElement(b []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			return element.Name.Local
+		}
+	}
+}
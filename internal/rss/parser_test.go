@@ -0,0 +1,215 @@
+package rss
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFixtures(t *testing.T) {
+	fetched := time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)
+	type item struct {
+		title, content, link string
+		pubTime              time.Time
+	}
+	tests := []struct {
+		file        string
+		contentType string
+		site        string
+		items       []item
+	}{
+		{
+			file:        "rss2.xml",
+			contentType: "application/rss+xml",
+			site:        "https://example.com/",
+			items: []item{
+				// Постоянная ссылка из guid предпочтительнее link
+				{"Первая новость", "Текст первой новости.", "https://example.com/news/1", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+				// guid не ссылка - берется link, нет даты - время опроса
+				{"Вторая новость", "Текст второй новости", "https://example.com/news/2", fetched},
+			},
+		},
+		{
+			file:        "atom.xml",
+			contentType: "application/atom+xml",
+			site:        "https://example.com/",
+			items: []item{
+				// Заголовок type="html" раскодируется и очищается от тэгов, из ссылок rel="alternate" выбирается text/html,
+				// published предпочтительнее updated
+				{"Выпуск Go 1.22 & новости", "Вышел Go 1.22.", "https://example.com/entries/1", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+				// Разметка type="xhtml", ссылка без rel, без published - время updated
+				{"Разметка xhtml", "Первый абзац.", "https://example.com/entries/2", time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)},
+				// Единственная ссылка rel="alternate" другого типа, без дат - время опроса
+				{"Без даты", "Простой текст & без разметки", "https://example.com/entries/3.pdf", fetched},
+			},
+		},
+		{
+			file:        "rdf.xml",
+			contentType: "application/rdf+xml",
+			site:        "https://example.com/",
+			items: []item{
+				// Описание очищается от разметки и скриптов, сущности раскодируются, пробелы схлопываются
+				{"Первая запись", "Текст первой записи & подробности.", "https://example.com/rdf/1", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+				// dc:date в формате W3C-DTF может быть только датой
+				{"Только дата", "Простой текст", "https://example.com/rdf/2", time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+				// Нет dc:date - время опроса
+				{"Без даты", "Запись без dc:date", "https://example.com/rdf/3", fetched},
+			},
+		},
+		{
+			file:        "feed.json",
+			contentType: "application/feed+json",
+			site:        "https://example.com/",
+			items: []item{
+				// content_text - простой текст, угловые скобки не принимаются за тэги
+				{"Простой текст", "plain <not tag> & stuff", "https://example.com/json/1", time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)},
+				// content_html предпочтительнее content_text, без url - external_url, без date_published - date_modified
+				{"Разметка", "Текст с разметкой", "https://example.com/json/2", time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)},
+				// summary - тоже простой текст
+				{"Краткое содержание", "a < b & c", "https://example.com/json/3", fetched},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Формат определяется и по типу содержимого, и только по документу
+			for _, contentType := range []string{tt.contentType, "text/plain"} {
+				site, news, err := parse(contentType, b, fetched)
+				if err != nil {
+					t.Fatalf("parse(%s): %v", contentType, err)
+				}
+				if site != tt.site {
+					t.Errorf("parse(%s): сайт %q, ожидался %q", contentType, site, tt.site)
+				}
+				if len(news) != len(tt.items) {
+					t.Fatalf("parse(%s): %d новостей, ожидалось %d", contentType, len(news), len(tt.items))
+				}
+				for i, want := range tt.items {
+					got := news[i]
+					if got.Title != want.title || got.Content != want.content || got.Link != want.link || got.PubTime != want.pubTime.Unix() {
+						t.Errorf("новость %d = {%q %q %q %d}, ожидалось %+v", i, got.Title, got.Content, got.Link, got.PubTime, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseRSSAliases(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "rss2.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, news, err := parse("application/rss+xml", b, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Новость со ссылкой из guid ищется и по link, по которому она могла быть сохранена раньше
+	if len(news[0].Aliases) != 1 || news[0].Aliases[0] != "https://example.com/click?to=1" {
+		t.Errorf("ссылки первой новости %q, ожидалась ссылка из link", news[0].Aliases)
+	}
+	if news[1].Aliases != nil {
+		t.Errorf("ссылки второй новости %q, ожидалось без других ссылок", news[1].Aliases)
+	}
+}
+
+func TestParseAtomXHTMLBody(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, news, err := parse("application/atom+xml", b, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Разметка xhtml сохраняется в полном тексте без обертки div
+	if want := "<p>Первый абзац.</p><p>Второй абзац.</p>"; news[1].Body != want {
+		t.Errorf("Body = %q, ожидалось %q", news[1].Body, want)
+	}
+	// Спецсимволы простого текста в полном тексте экранируются
+	if want := "Простой текст &amp; без разметки"; news[2].Body != want {
+		t.Errorf("Body = %q, ожидалось %q", news[2].Body, want)
+	}
+}
+
+func TestParseSummaryAndContent(t *testing.T) {
+	// Записи с кратким содержанием и полным текстом: краткое содержание новости - из первого, полный текст - из второго
+	tests := []struct {
+		file        string
+		contentType string
+		index       int
+		content     string
+		body        string
+	}{
+		{"rss2.xml", "application/rss+xml", 1, "Текст второй новости", "<p>Текст второй новости.</p><p>Подробности.</p>"},
+		{"atom.xml", "application/atom+xml", 0, "Вышел Go 1.22.", "<p>Полный текст</p>"},
+		{"rdf.xml", "application/rdf+xml", 1, "Простой текст", "<p>Простой текст.</p><p>Полный текст записи.</p>"},
+		{"feed.json", "application/feed+json", 2, "a < b & c", "<p>Полный текст</p>"},
+	}
+	for _, tt := range tests {
+		b, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, news, err := parse(tt.contentType, b, time.Now())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.file, err)
+		}
+		got := news[tt.index]
+		if got.Content != tt.content || got.Body != tt.body {
+			t.Errorf("%s: новость %d = {%q %q}, ожидалось {%q %q}", tt.file, tt.index, got.Content, got.Body, tt.content, tt.body)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		format      string
+	}{
+		{"rss2.xml", "application/rss+xml", "RSS 2.0"},
+		{"atom.xml", "application/atom+xml", "Atom 1.0"},
+		{"rdf.xml", "application/rdf+xml", "RSS 1.0 (RDF)"},
+		{"feed.json", "application/feed+json", "JSON Feed"},
+	}
+	for _, tt := range tests {
+		b, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Формат определяется по документу и при неточном или чужом типе содержимого
+		for _, contentType := range []string{tt.contentType, tt.contentType + "; charset=utf-8", "text/xml", "application/rss+xml", ""} {
+			p, err := detect(contentType, b)
+			if err != nil || p.name != tt.format {
+				t.Errorf("detect(%q, %s) = %q, %v, ожидалось %q", contentType, tt.file, p.name, err, tt.format)
+			}
+		}
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	if _, _, err := parse("text/html", []byte("<html><body>не фид</body></html>"), time.Now()); err == nil {
+		t.Error("parse для html страницы не вернул ошибку")
+	}
+}
+
+func TestParseJSONFeedText(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "feed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, news, err := parse("application/feed+json", b, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Простой текст сохраняется в полном тексте целиком, экранированным
+	if want := "plain &lt;not tag&gt; &amp; stuff"; news[0].Body != want {
+		t.Errorf("Body = %q, ожидалось %q", news[0].Body, want)
+	}
+}
@@ -0,0 +1,43 @@
+package rss
+
+import "encoding/xml"
+
+// Набор вложенных структур для раскодировки фида RSS 1.0 (RDF), записи лежат рядом с channel
+type rdfFeed struct {
+	Channel struct {
+		Link string `xml:"link"`
+	} `xml:"channel"`
+	Items []rdfItem `xml:"item"`
+}
+
+type rdfItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"` // Полный текст, модуль content
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+// Метод разбирает адрес сайта и записи из фида RSS 1.0 (RDF)
+func parseRDF(b []byte) (string, []entry, error) {
+	var feed rdfFeed
+	// Раскодируем xml в структуру
+	err := xml.Unmarshal(b, &feed)
+	if err != nil {
+		return "", []entry{}, err
+	}
+
+	var entries []entry
+	// Итерируем по массиву записей
+	for _, item := range feed.Items {
+		var e entry
+		e.Title = item.Title
+		e.Summary = item.Description
+		e.Content = item.Content
+		e.Link = item.Link
+		// Время публикации в модуле Dublin Core задается в формате W3C-DTF, допускается только дата
+		e.PubTime, _ = parseDate(item.Date)
+		entries = append(entries, e)
+	}
+	return feed.Channel.Link, entries, nil
+}
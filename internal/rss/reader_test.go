@@ -0,0 +1,77 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/antibaloo/sf-final-project/internal/storage/memory"
+)
+
+// Хранилище, в котором не записывается пакет новостей с заданным номером
+type failingStore struct {
+	storage.Store
+	failBatch int // Номер пакета с ошибкой, с 1
+	batches   int // Вызовов AddNewsBatch
+}
+
+func (s *failingStore) AddNewsBatch(ctx context.Context, news []storage.NewsShortDetailed) ([]storage.BatchResult, error) {
+	s.batches++
+	if s.batches == s.failBatch {
+		return nil, fmt.Errorf("ошибка записи пакета %d", s.batches)
+	}
+	return s.Store.AddNewsBatch(ctx, news)
+}
+
+// Возвращает count новостей с разными ссылками
+func feedNews(count int) []storage.NewsShortDetailed {
+	news := make([]storage.NewsShortDetailed, count)
+	for i := range news {
+		news[i] = storage.NewsShortDetailed{Title: fmt.Sprintf("Новость %d", i), Link: fmt.Sprintf("https://example.com/%d", i)}
+	}
+	return news
+}
+
+func TestWriteNewsFailedBatch(t *testing.T) {
+	db := &failingStore{Store: memory.NewStore(), failBatch: 2}
+	r, err := CreateService([]byte(`{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.writeCtx = context.Background()
+	// Три пакета, второй не записывается: остальные пакеты записываются, новости второго считаются с ошибкой
+	total := 2*writeBatchSize + 10
+	counts, failed := r.writeNews("https://example.com/feed", feedNews(total))
+	if !failed {
+		t.Error("ошибка пакета не отмечена")
+	}
+	if db.batches != 3 {
+		t.Errorf("записано пакетов %d, ожидалось 3", db.batches)
+	}
+	if counts[storage.NewsInserted] != writeBatchSize+10 || counts[storage.NewsFailed] != writeBatchSize {
+		t.Errorf("результаты %v, ожидалось %d добавлено и %d с ошибкой", counts, writeBatchSize+10, writeBatchSize)
+	}
+	if _, count, err := db.News(context.Background(), storage.NewsQuery{Limit: 1}); err != nil || count != writeBatchSize+10 {
+		t.Errorf("новостей в БД %d, %v", count, err)
+	}
+	if len(r.writes) != 0 {
+		t.Errorf("незавершенных записей %d", len(r.writes))
+	}
+}
+
+func TestWriteNewsAborted(t *testing.T) {
+	db := &failingStore{Store: memory.NewStore()}
+	r, err := CreateService([]byte(`{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Запись прервана в Stop: новости не пишутся и все считаются с ошибкой
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.writeCtx = ctx
+	counts, failed := r.writeNews("https://example.com/feed", feedNews(writeBatchSize+1))
+	if !failed || db.batches != 0 || counts[storage.NewsFailed] != writeBatchSize+1 {
+		t.Errorf("прерванная запись: %v, %t, пакетов %d", counts, failed, db.batches)
+	}
+}
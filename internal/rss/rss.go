@@ -1,18 +1,28 @@
 package rss
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
-	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/antibaloo/sf-final-project/internal/storage"
 )
 
-var errDuplicate = `ERROR: duplicate key value violates unique constraint "news_link_key" (SQLSTATE 23505)`
+var (
+	ErrUnreachable   = errors.New("фид недоступен")          // Ошибка соединения с источником или ответ не 200
+	ErrUnknownFormat = errors.New("неизвестный формат фида") // Ответ источника не является фидом поддерживаемого формата
+)
+
+// Наибольший размер фида при проверке
+const maxCheckSize = 10 << 20
 
 // Набор вложенных структур для раскодировки xml rss фида
 type feed struct {
@@ -21,23 +31,82 @@ type feed struct {
 }
 
 type channel struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	Items       []item `xml:"item"`
+	Title       string   `xml:"title"`
+	Description string   `xml:"description"`
+	Links       []string `xml:"link"` // Ссылка на сайт, рядом могут быть пустые atom:link
+	Items       []item   `xml:"item"`
 }
 
 type item struct {
-	Title   string `xml:"title"`
-	Content string `xml:"description"`
-	Link    string `xml:"link"`
-	PubTime string `xml:"pubDate"`
+	mediaRSS
+	Title      string      `xml:"title"`
+	Summary    string      `xml:"description"`
+	Content    string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"` // Полный текст, модуль content
+	Link       string      `xml:"link"`
+	PubTime    string      `xml:"pubDate"`
+	GUID       guid        `xml:"guid"`
+	Enclosures []enclosure `xml:"enclosure"`
+}
+
+// Уникальный идентификатор записи RSS 2.0, по-умолчанию является постоянной ссылкой на запись
+type guid struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
 }
 
+// Возвращает постоянную ссылку на запись из guid или пустую строку, если guid не является ссылкой
+func (g guid) permaLink() string {
+	link := strings.TrimSpace(g.Value)
+	if strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false") || !safeURL(link) {
+		return ""
+	}
+	return link
+}
+
+// Значения по-умолчанию для параметров повторных попыток опроса
+const (
+	minBackoff              = 30 * time.Second // Пауза после первой ошибки подряд
+	defaultMaxBackoff       = 60               // Наибольшая пауза между попытками, в минутах
+	defaultFailureThreshold = 5                // Количество ошибок подряд, после которого фид считается неисправным
+	defaultSyncPeriod       = 10               // Период сверки списка источников с БД, в секундах
+	defaultWorkers          = 4                // Количество одновременных опросов
+	defaultHostDelay        = 1                // Пауза между опросами одного хоста, в секундах
+	defaultStopTimeout      = 10               // Время ожидания записи новостей при остановке, в секундах
+	writeBatchSize          = 100              // Наибольшее количество новостей в одном пакете записи в БД
+)
+
 type rssReader struct {
-	URLs          []string      `json:"rss"`
-	RequestPeriod time.Duration `json:"request_period"`
-	db            storage.Store
+	URLs             []string      `json:"rss"`                 // Адреса фидов для заполнения пустой таблицы источников
+	RequestPeriod    time.Duration `json:"request_period"`      // Период опроса по-умолчанию, в минутах
+	MaxBackoff       time.Duration `json:"max_backoff"`         // Наибольшая пауза между попытками после ошибок, в минутах
+	FailureThreshold int           `json:"failure_threshold"`   // Количество ошибок подряд, после которого фид считается неисправным
+	SyncPeriod       time.Duration `json:"sources_sync_period"` // Период сверки списка источников с БД, в секундах
+	Workers          int           `json:"workers"`             // Количество одновременных опросов
+	HostDelay        time.Duration `json:"host_delay"`          // Пауза между опросами одного хоста, в секундах
+	StopTimeout      time.Duration `json:"stop_timeout"`        // Время ожидания записи новостей при остановке, в секундах
+	db               storage.Store
+	clock            clock
+	limiter          *limiter
+	running          map[int]feedReader // Запущенные чтения по идентификатору источника
+	cancel           context.CancelFunc // Останавливает опросы
+	writeCtx         context.Context    // Контекст записи в БД, не отменяется остановкой опросов
+	abortWrites      context.CancelFunc // Прерывает запись в БД
+	wg               sync.WaitGroup     // Запущенные горутины ридера
+	mu               sync.Mutex
+	writes           map[*writeProgress]struct{} // Идущие записи новостей
+}
+
+// Ход записи новостей из фида в БД
+type writeProgress struct {
+	url     string // Адрес фида
+	total   int    // Новостей в фиде
+	written int    // Обработано новостей
+}
+
+// Запущенное чтение источника
+type feedReader struct {
+	source storage.Source     // Источник на момент запуска
+	cancel context.CancelFunc // Останавливает чтение
 }
 
 // Метод создает структуру ридера новостей
@@ -53,100 +122,465 @@ func CreateService(config []byte, db storage.Store) (*rssReader, error) {
 	if err != nil {
 		return &rssReader{}, err
 	}
+	if rss.MaxBackoff <= 0 {
+		rss.MaxBackoff = defaultMaxBackoff
+	}
+	if rss.FailureThreshold <= 0 {
+		rss.FailureThreshold = defaultFailureThreshold
+	}
+	if rss.SyncPeriod <= 0 {
+		rss.SyncPeriod = defaultSyncPeriod
+	}
+	if rss.Workers <= 0 {
+		rss.Workers = defaultWorkers
+	}
+	if rss.StopTimeout <= 0 {
+		rss.StopTimeout = defaultStopTimeout
+	}
+	if rss.HostDelay < 0 {
+		rss.HostDelay = 0
+	} else if rss.HostDelay == 0 {
+		rss.HostDelay = defaultHostDelay
+	}
 	rss.db = db
+	rss.clock = systemClock{}
+	rss.limiter = newLimiter(rss.clock, rss.Workers, time.Second*rss.HostDelay)
+	rss.running = map[int]feedReader{}
+	rss.writes = map[*writeProgress]struct{}{}
 	return &rss, nil
 }
 
-// Метод запускает ридер новостей по одному на каждый rss канал
-func (r *rssReader) Start() {
+// Метод запускает ридер новостей: по одному чтению на каждый включенный источник из БД. Список источников
+// периодически сверяется с БД, чтения новых и измененных источников запускаются, удаленных и отключенных -
+// останавливаются. Опросы прекращаются при отмене контекста или вызове Stop, начатая запись новостей в БД
+// при этом продолжается, см. Stop.
+func (r *rssReader) Start(ctx context.Context) {
+	r.writeCtx, r.abortWrites = context.WithCancel(context.WithoutCancel(ctx))
+	ctx, r.cancel = context.WithCancel(ctx)
+	if err := r.seed(ctx); err != nil {
+		fmt.Printf(
+			"%v: при заполнении списка источников из конфигурации произошла ошибка: %s\n",
+			time.Now().Format("02.01.2006 15:04:05 MST"),
+			err.Error(),
+		)
+	}
+	r.sync(ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for r.sleep(ctx, time.Second*r.SyncPeriod) {
+			r.sync(ctx)
+		}
+	}()
+}
+
+// Метод останавливает ридер: прекращает опросы фидов и ждет окончания начатой записи новостей в БД
+// не дольше StopTimeout секунд, после чего прерывает запись. О прерванных записях сообщается в лог.
+func (r *rssReader) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	defer r.abortWrites()
+	fmt.Printf("%v: останавливаем ридер новостей\n", time.Now().Format("02.01.2006 15:04:05 MST"))
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		fmt.Printf("%v: ридер новостей остановлен\n", time.Now().Format("02.01.2006 15:04:05 MST"))
+		return
+	case <-r.clock.After(time.Second * r.StopTimeout):
+	}
+	// Запоминаем незавершенные записи и прерываем их
+	r.mu.Lock()
+	interrupted := make([]*writeProgress, 0, len(r.writes))
+	for progress := range r.writes {
+		interrupted = append(interrupted, progress)
+	}
+	r.mu.Unlock()
+	r.abortWrites()
+	<-done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, progress := range interrupted {
+		fmt.Printf(
+			"%v: запись новостей из канала %s прервана: обработано %d из %d новостей\n",
+			time.Now().Format("02.01.2006 15:04:05 MST"),
+			progress.url, progress.written, progress.total,
+		)
+	}
+	fmt.Printf(
+		"%v: ридер новостей остановлен по таймауту, прервана запись из %d каналов\n",
+		time.Now().Format("02.01.2006 15:04:05 MST"),
+		len(interrupted),
+	)
+}
+
+// Метод заполняет пустой список источников адресами из конфигурации, при первом запуске
+func (r *rssReader) seed(ctx context.Context) error {
+	sources, err := r.db.Sources(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sources) > 0 {
+		return nil
+	}
 	for _, url := range r.URLs {
-		go readNews(r.db, url, r.RequestPeriod)
+		_, err := r.db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: url}})
+		if err != nil && !errors.Is(err, storage.ErrDuplicateSource) {
+			return err
+		}
 	}
+	return nil
 }
 
-// Метод читает новости из канала с заданный периодом
-func readNews(db storage.Store, url string, period time.Duration) {
+// Метод сверяет запущенные чтения со списком источников в БД
+func (r *rssReader) sync(ctx context.Context) {
+	sources, err := r.db.Sources(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf(
+				"%v: при загрузке списка источников произошла ошибка: %s\n",
+				time.Now().Format("02.01.2006 15:04:05 MST"),
+				err.Error(),
+			)
+		}
+		return
+	}
+	enabled := map[int]storage.Source{}
+	for _, src := range sources {
+		if src.Enabled {
+			enabled[src.Id] = src
+		}
+	}
+	// Останавливаем чтения удаленных, отключенных и измененных источников
+	for id, feed := range r.running {
+		src, exist := enabled[id]
+		if exist && src.URL == feed.source.URL && src.PollInterval == feed.source.PollInterval {
+			continue
+		}
+		feed.cancel()
+		delete(r.running, id)
+	}
+	// Запускаем чтения новых источников
+	for id, src := range enabled {
+		if _, exist := r.running[id]; exist {
+			continue
+		}
+		feedCtx, cancel := context.WithCancel(ctx)
+		r.running[id] = feedReader{source: src, cancel: cancel}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.readNews(feedCtx, src)
+		}()
+	}
+}
+
+// Метод читает новости из источника с заданным периодом, после ошибок чтения повторяет попытки с нарастающей паузой
+func (r *rssReader) readNews(ctx context.Context, src storage.Source) {
+	url, state := src.URL, src.FeedState
+	// Период опроса источника, если не задан - период ридера
+	period := r.RequestPeriod
+	if src.PollInterval > 0 {
+		period = time.Duration(src.PollInterval)
+	}
 	fmt.Printf("%v: чтение новостей из канала %s начато\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
 	for {
-		news, err := parseFeed(url)
+		next := state
+		// Ждем очереди на опрос: пул ограничивает число одновременных опросов, хост опрашивается по одному
+		release, err := r.limiter.acquire(ctx, storage.Host(url))
+		if err != nil {
+			fmt.Printf("%v: чтение новостей из канала %s остановлено\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
+			return
+		}
+		news, modified, err := parseFeed(ctx, &next, r.clock.Now())
+		release()
+		if ctx.Err() != nil {
+			fmt.Printf("%v: чтение новостей из канала %s остановлено\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
+			return
+		}
 		if err != nil {
 			fmt.Printf(
 				"%v: при чтении новостей из канала %s произошла ошибка: %s\n",
 				time.Now().Format("02.01.2006 15:04:05 MST"),
 				url, err.Error(),
 			)
-			continue
-		}
-		countNews := 0
-		for _, n := range news {
-			err := db.AddNews(n)
-			if err != nil {
-				// Игнорируем ошибку дубликата уникального поля, т.к. сами его сделали (поле ссылка на новость уникально для
-				// предотвращения повторно запсии новости в БД)
-				if err.Error() != errDuplicate {
+			// Заголовки не меняем, учитываем ошибку и ждем перед следующей попыткой
+			next = state
+			next.LastStatus, next.LastFetchAt = storage.FetchError, r.clock.Now().Unix()
+			next.Failures++
+			next.LastError = err.Error()
+			next.LastErrorAt = r.clock.Now().Unix()
+			if next.Failures >= r.FailureThreshold {
+				if state.Healthy {
 					fmt.Printf(
-						"%v: при попытке записи новости из канала %s в БД произошла ошибка: %s\n",
+						"%v: канал %s помечен неисправным после %d ошибок подряд\n",
 						time.Now().Format("02.01.2006 15:04:05 MST"),
-						url, err.Error(),
+						url, next.Failures,
 					)
 				}
-			} else {
-				countNews++
+				next.Healthy = false
+			}
+			// Счетчик ошибок ведем и при неудачном сохранении, чтобы пауза продолжала расти
+			r.saveFeedState(r.writeCtx, state, next)
+			state = next
+			delay := backoff(next.Failures, time.Minute*r.MaxBackoff)
+			fmt.Printf(
+				"%v: следующая попытка чтения канала %s через %s\n",
+				time.Now().Format("02.01.2006 15:04:05 MST"),
+				url, delay.Round(time.Second),
+			)
+			if !r.sleep(ctx, delay) {
+				fmt.Printf("%v: чтение новостей из канала %s остановлено\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
+				return
 			}
+			continue
+		}
+		// Фид прочитан, сбрасываем счетчик ошибок
+		if !state.Healthy {
+			fmt.Printf("%v: канал %s снова исправен\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
+		}
+		next.Failures = 0
+		next.Healthy = true
+		next.LastStatus, next.LastFetchAt = storage.FetchOK, r.clock.Now().Unix()
+		if modified {
+			for i := range news {
+				news[i].Source = &storage.NewsSource{Id: src.Id}
+			}
+			counts, failed := r.writeNews(url, news)
+			fmt.Printf(
+				"%v: получено %d новостей, обновлено %d, без изменений %d, с ошибкой %d из фида: %s \n",
+				time.Now().Format("02.01.2006 15:04:05 MST"),
+				counts[storage.NewsInserted], counts[storage.NewsUpdated], counts[storage.NewsUnchanged], counts[storage.NewsFailed], url,
+			)
+			// Заголовки сохраняем только если все новости записаны, иначе следующий опрос вернет 304 и они потеряются
+			if failed {
+				next.ETag, next.LastModified = state.ETag, state.LastModified
+			}
+		} else {
+			next.LastStatus = storage.FetchNotModified
+			fmt.Printf("%v: фид не изменился: %s \n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
 		}
-		fmt.Printf("%v: получено %d новостей из фида: %s \n", time.Now().Format("02.01.2006 15:04:05 MST"), countNews, url)
-		time.Sleep(time.Minute * period)
+		state = r.saveFeedState(r.writeCtx, state, next)
+		if !r.sleep(ctx, time.Minute*period) {
+			fmt.Printf("%v: чтение новостей из канала %s остановлено\n", time.Now().Format("02.01.2006 15:04:05 MST"), url)
+			return
+		}
+	}
+}
+
+// Метод записывает новости из фида в БД пакетами, чтобы ход записи был виден в Stop. Возвращает количество
+// новостей по результатам добавления и признак того, что часть новостей не записана. Ошибка пакета не прерывает
+// запись остальных пакетов, новости не записанного пакета считаются с ошибкой. Запись не прерывается остановкой
+// опросов, только по истечении времени ожидания в Stop, тогда оставшиеся новости тоже считаются с ошибкой.
+func (r *rssReader) writeNews(url string, news []storage.NewsShortDetailed) (counts map[string]int, failed bool) {
+	counts = map[string]int{}
+	progress := r.startWrite(url, len(news))
+	defer r.finishWrite(progress)
+	for start := 0; start < len(news); start += writeBatchSize {
+		if r.writeCtx.Err() != nil {
+			counts[storage.NewsFailed] += len(news) - start
+			return counts, true
+		}
+		batch := news[start:min(start+writeBatchSize, len(news))]
+		// Новость, которая уже есть в БД (в том числе пришедшая из другого фида), не добавляется и ошибкой не считается,
+		// измененная в этом фиде новость обновляется
+		results, err := r.db.AddNewsBatch(r.writeCtx, batch)
+		if err != nil {
+			failed = true
+			counts[storage.NewsFailed] += len(batch)
+			// О прерванной при остановке записи сообщает Stop
+			if r.writeCtx.Err() == nil {
+				fmt.Printf(
+					"%v: при попытке записи %d новостей из канала %s в БД произошла ошибка: %s\n",
+					time.Now().Format("02.01.2006 15:04:05 MST"),
+					len(batch), url, err.Error(),
+				)
+			}
+			continue
+		}
+		r.mu.Lock()
+		progress.written += len(batch)
+		r.mu.Unlock()
+		for _, result := range results {
+			counts[result.Result]++
+			if result.Err != nil {
+				failed = true
+				fmt.Printf(
+					"%v: при попытке записи новости из канала %s в БД произошла ошибка: %s\n",
+					time.Now().Format("02.01.2006 15:04:05 MST"),
+					url, result.Err.Error(),
+				)
+			}
+		}
+	}
+	return counts, failed
+}
+
+// Метод регистрирует начало записи новостей из фида
+func (r *rssReader) startWrite(url string, total int) *writeProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	progress := &writeProgress{url: url, total: total}
+	r.writes[progress] = struct{}{}
+	return progress
+}
+
+// Метод регистрирует окончание записи новостей из фида
+func (r *rssReader) finishWrite(progress *writeProgress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.writes, progress)
+}
+
+// Метод сохраняет новое состояние опроса фида, если оно изменилось. Возвращает состояние, которое хранится в БД
+func (r *rssReader) saveFeedState(ctx context.Context, state, next storage.FeedState) storage.FeedState {
+	if ctx.Err() != nil || next == state {
+		return state
+	}
+	if err := r.db.SaveFeedState(ctx, next); err != nil {
+		fmt.Printf(
+			"%v: при сохранении состояния канала %s произошла ошибка: %s\n",
+			time.Now().Format("02.01.2006 15:04:05 MST"),
+			next.URL, err.Error(),
+		)
+		return state
+	}
+	return next
+}
+
+// Метод рассчитывает паузу перед повторной попыткой после failures ошибок подряд: пауза удваивается
+// с каждой ошибкой, начиная с minBackoff, но не превышает maxBackoff. Вторая половина паузы случайна,
+// чтобы неисправные фиды не опрашивались одновременно.
+func backoff(failures int, maxBackoff time.Duration) time.Duration {
+	delay := maxBackoff
+	if failures < 1 {
+		failures = 1
+	}
+	// Ограничиваем сдвиг, чтобы избежать переполнения
+	if failures < 32 && minBackoff<<(failures-1) < maxBackoff {
+		delay = minBackoff << (failures - 1)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Метод ждет заданное время, возвращает false, если ожидание прервано отменой контекста
+func (r *rssReader) sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-r.clock.After(d):
+		return true
 	}
 }
 
-// Метод разбирает новости из канала
-func parseFeed(url string) ([]storage.NewsShortDetailed, error) {
+// Функция загружает фид по адресу url и проверяет, что он в поддерживаемом формате, не сохраняя новости.
+// Ошибка оборачивает ErrUnreachable, если фид не удалось загрузить, или ErrUnknownFormat, если формат не определен.
+func CheckFeed(ctx context.Context, url string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: источник вернул %s", ErrUnreachable, response.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(response.Body, maxCheckSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	if len(b) > maxCheckSize {
+		return fmt.Errorf("%w: фид больше %d байт", ErrUnknownFormat, maxCheckSize)
+	}
+	contentType := response.Header.Get("Content-Type")
+	b, err = toUTF8(contentType, b)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+	_, err = detect(contentType, b)
+	return err
+}
+
+// Метод загружает фид условным запросом и разбирает новости из него. Если фид не изменился
+// с прошлого опроса (ответ 304), возвращает modified = false. Заголовки ETag и Last-Modified
+// полного ответа и адрес сайта из фида записываются в state. Новости без разборчивой даты публикации
+// получают время опроса fetched.
+func parseFeed(ctx context.Context, state *storage.FeedState, fetched time.Time) (news []storage.NewsShortDetailed, modified bool, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, state.URL, nil)
+	if err != nil {
+		return []storage.NewsShortDetailed{}, false, err
+	}
+	if state.ETag != "" {
+		request.Header.Set("If-None-Match", state.ETag)
+	}
+	if state.LastModified != "" {
+		request.Header.Set("If-Modified-Since", state.LastModified)
+	}
 	// Сохраняем ответ на запрос по адресу url
-	response, err := http.Get(url)
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
-		return []storage.NewsShortDetailed{}, err
+		return []storage.NewsShortDetailed{}, false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		return []storage.NewsShortDetailed{}, false, nil
+	case http.StatusOK:
+	default:
+		return []storage.NewsShortDetailed{}, false, fmt.Errorf("источник вернул %s", response.Status)
 	}
 
 	// Читаем тело ответа в массив байт
 	b, err := io.ReadAll(response.Body)
 	if err != nil {
-		return []storage.NewsShortDetailed{}, err
+		return []storage.NewsShortDetailed{}, false, err
 	}
+	site, news, err := parse(response.Header.Get("Content-Type"), b, fetched)
+	if err != nil {
+		return []storage.NewsShortDetailed{}, false, err
+	}
+	state.SiteURL = site
+	state.ETag = response.Header.Get("ETag")
+	state.LastModified = response.Header.Get("Last-Modified")
+	return news, true, nil
+}
 
+// Метод разбирает адрес сайта и записи из фида RSS 2.0
+func parseRSS(b []byte) (string, []entry, error) {
 	var feed feed
-	// Раскодиреум xml в структуру
-	err = xml.Unmarshal(b, &feed)
+	// Раскодируем xml в структуру
+	err := xml.Unmarshal(b, &feed)
 	if err != nil {
-		return []storage.NewsShortDetailed{}, err
+		return "", []entry{}, err
 	}
 
-	var news []storage.NewsShortDetailed
+	var entries []entry
 	// Итерируем по массиву новостей
 	for _, item := range feed.Channel.Items {
-		var n storage.NewsShortDetailed
-		n.Title = item.Title
-		// Удаляем html тэги с помощью регулярного выражения
-		n.Content = stripHtmlTags(item.Content)
-		n.Link = item.Link
-		// Парсим время публикации по одному формату
-		t, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", item.PubTime)
-		// Если получаем ошибку, то парсим другой формат
-		if err != nil {
-			t, err = time.Parse("Mon, 2 Jan 2006 15:04:05 GMT", item.PubTime)
+		var e entry
+		e.Title = item.Title
+		e.Summary = item.Summary
+		e.Content = item.Content
+		// Постоянная ссылка из guid надежнее link, в котором бывают ссылки счетчиков переходов
+		e.Link = firstNonEmpty(item.GUID.permaLink(), item.Link)
+		// Раньше новости сохранялись по link, по нему ищется уже сохраненная новость
+		if item.Link != "" && item.Link != e.Link {
+			e.Aliases = []string{item.Link}
 		}
-		if err != nil {
-			return []storage.NewsShortDetailed{}, err
+		e.PubTime, _ = parseDate(item.PubTime)
+		for _, enc := range item.Enclosures {
+			e.Media = addMedia(e.Media, storage.Media{URL: enc.URL, Type: enc.Type})
 		}
-		n.PubTime = t.Unix()
-		news = append(news, n)
+		e.Media = addMedia(e.Media, item.media()...)
+		entries = append(entries, e)
 	}
-	return news, nil
-}
-
-func stripHtmlTags(s string) string {
-	// Регулярное выражение для удаления html тэгов
-	const regex = `<.*?>`
-	r := regexp.MustCompile(regex)
-	// Удаляем html тэги с помощью регулярного выражения
-	return r.ReplaceAllString(s, "")
+	return firstNonEmpty(feed.Channel.Links...), entries, nil
 }
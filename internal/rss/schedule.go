@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Источник текущего времени и таймеров ридера, в тестах подменяется управляемыми часами
+type clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+// Системные часы
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Ограничитель опросов фидов: одновременно выполняется не больше workers опросов, один хост опрашивается
+// не больше чем одним опросом за раз и не раньше, чем через hostDelay после окончания предыдущего опроса
+type limiter struct {
+	clock     clock
+	slots     chan struct{} // Занятые места в пуле опросов
+	hostDelay time.Duration
+	mu        sync.Mutex
+	hosts     map[string]*hostSlot
+}
+
+// Очередь опросов одного хоста
+type hostSlot struct {
+	busy chan struct{} // Занят, пока хост опрашивается
+	last time.Time     // Время окончания последнего опроса, меняется только при занятом хосте
+}
+
+// Конструктор ограничителя опросов
+func newLimiter(clock clock, workers int, hostDelay time.Duration) *limiter {
+	return &limiter{
+		clock:     clock,
+		slots:     make(chan struct{}, workers),
+		hostDelay: hostDelay,
+		hosts:     map[string]*hostSlot{},
+	}
+}
+
+// Возвращает очередь опросов хоста
+func (l *limiter) host(host string) *hostSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, exist := l.hosts[host]
+	if !exist {
+		h = &hostSlot{busy: make(chan struct{}, 1)}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// Метод ждет очереди на опрос хоста и свободного места в пуле. Возвращает функцию освобождения,
+// которую нужно вызвать по окончании опроса, или ошибку при отмене контекста.
+func (l *limiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+	// Сначала ждем хост, чтобы ожидающие своей очереди опросы не занимали места в пуле
+	select {
+	case h.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if !h.last.IsZero() {
+		if wait := h.last.Add(l.hostDelay).Sub(l.clock.Now()); wait > 0 {
+			select {
+			case <-l.clock.After(wait):
+			case <-ctx.Done():
+				<-h.busy
+				return nil, ctx.Err()
+			}
+		}
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		<-h.busy
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-l.slots
+		h.last = l.clock.Now()
+		<-h.busy
+	}, nil
+}
@@ -0,0 +1,184 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Управляемые часы: время идет только при вызове Advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Переводит часы вперед и срабатывает наступившие таймеры
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = timers
+}
+
+// Количество ожидающих таймеров
+func (c *fakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Результат ожидания очереди на опрос
+type acquired struct {
+	release func()
+	err     error
+}
+
+// Запускает ожидание очереди в отдельной горутине
+func acquireAsync(ctx context.Context, l *limiter, host string) <-chan acquired {
+	done := make(chan acquired, 1)
+	go func() {
+		release, err := l.acquire(ctx, host)
+		done <- acquired{release, err}
+	}()
+	return done
+}
+
+// Проверяет, что опрос начался, и возвращает функцию его окончания
+func started(t *testing.T, done <-chan acquired) func() {
+	t.Helper()
+	select {
+	case a := <-done:
+		if a.err != nil {
+			t.Fatalf("acquire: %v", a.err)
+		}
+		return a.release
+	case <-time.After(time.Second):
+		t.Fatal("опрос не начался")
+		return nil
+	}
+}
+
+// Проверяет, что опрос ждет своей очереди
+func waiting(t *testing.T, done <-chan acquired) {
+	t.Helper()
+	select {
+	case a := <-done:
+		t.Fatalf("опрос начался без очереди: %v", a.err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Ждет, пока ограничитель заведет таймер
+func waitTimers(t *testing.T, c *fakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.Timers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ожидалось %d таймеров, заведено %d", n, c.Timers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiterWorkers(t *testing.T) {
+	l := newLimiter(newFakeClock(), 2, 0)
+	ctx := context.Background()
+	releaseA := started(t, acquireAsync(ctx, l, "a.example"))
+	releaseB := started(t, acquireAsync(ctx, l, "b.example"))
+	// Пул занят, третий хост ждет освобождения места
+	third := acquireAsync(ctx, l, "c.example")
+	waiting(t, third)
+	releaseA()
+	releaseC := started(t, third)
+	releaseB()
+	releaseC()
+}
+
+func TestLimiterHostSerial(t *testing.T) {
+	l := newLimiter(newFakeClock(), 2, 0)
+	ctx := context.Background()
+	releaseA := started(t, acquireAsync(ctx, l, "a.example"))
+	// Второй опрос того же хоста ждет окончания первого и не занимает место в пуле
+	second := acquireAsync(ctx, l, "a.example")
+	waiting(t, second)
+	started(t, acquireAsync(ctx, l, "b.example"))()
+	releaseA()
+	started(t, second)()
+}
+
+func TestLimiterHostDelay(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(clock, 4, 10*time.Second)
+	ctx := context.Background()
+	// Первый опрос хоста паузы не ждет
+	release := started(t, acquireAsync(ctx, l, "a.example"))
+	clock.Advance(3 * time.Second)
+	release()
+	// Следующий опрос ждет host_delay от окончания предыдущего, а не от его начала
+	next := acquireAsync(ctx, l, "a.example")
+	waitTimers(t, clock, 1)
+	clock.Advance(9 * time.Second)
+	waiting(t, next)
+	clock.Advance(time.Second)
+	started(t, next)()
+	// Если пауза уже прошла, опрос начинается сразу
+	clock.Advance(15 * time.Second)
+	started(t, acquireAsync(ctx, l, "a.example"))()
+	// Пауза одного хоста не задерживает другие
+	started(t, acquireAsync(ctx, l, "b.example"))()
+}
+
+func TestLimiterCancel(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(clock, 1, time.Second)
+	release := started(t, acquireAsync(context.Background(), l, "a.example"))
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := acquireAsync(ctx, l, "a.example")
+	waiting(t, waiter)
+	cancel()
+	if a := <-waiter; !errors.Is(a.err, context.Canceled) {
+		t.Fatalf("acquire после отмены: %v", a.err)
+	}
+	// Отмененное ожидание не занимает ни хост, ни пул
+	release()
+	clock.Advance(time.Second)
+	started(t, acquireAsync(context.Background(), l, "a.example"))()
+}
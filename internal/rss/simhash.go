@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Служебные слова, которые есть почти в любой новости и не отличают одну новость от другой
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`и в во на с со по за из у о об от до не что как это для при а но или же то его ее её их он она
+		они мы вы был была были будет быть так также который которая которые которое после более
+		the a an of to in on and or for with by at from is are was were be been has have had its it as that this after over new`) {
+		stopWords[w] = true
+	}
+}
+
+// Параметры признаков отпечатка
+const (
+	simhashMinWord     = 3 // Наименьшая длина слова в буквах, более короткие слова не учитываются
+	simhashStem        = 5 // Слова сокращаются до первых букв, чтобы разные формы слова давали один признак
+	simhashTitleWeight = 2 // Вес слов заголовка относительно слов краткого содержания
+)
+
+// Возвращает отпечаток SimHash новости: у похожих текстов отпечатки отличаются в немногих битах.
+// Признаки - слова без служебных, сокращенные до simhashStem букв, вес признака - количество его повторов,
+// слова заголовка весят simhashTitleWeight. Отдельные слова, а не пары слов, устойчивы к перестановкам
+// и замене слов при перепечатке. Для текста без слов возвращает 0.
+func simhash(title, summary string) uint64 {
+	features := map[string]int{}
+	addWords(features, title, simhashTitleWeight)
+	addWords(features, summary, 1)
+	if len(features) == 0 {
+		return 0
+	}
+	// Каждый признак голосует за значения битов отпечатка своим хэшем с весом признака
+	var votes [64]int
+	for f, weight := range features {
+		h := fnv.New64a()
+		h.Write([]byte(f))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				votes[bit] += weight
+			} else {
+				votes[bit] -= weight
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit, v := range votes {
+		if v > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// Добавляет слова текста в признаки отпечатка с весом weight
+func addWords(features map[string]int, text string, weight int) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		runes := []rune(w)
+		if len(runes) < simhashMinWord || stopWords[w] {
+			continue
+		}
+		if len(runes) > simhashStem {
+			runes = runes[:simhashStem]
+		}
+		features[string(runes)] += weight
+	}
+}
@@ -0,0 +1,109 @@
+package rss
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Новость из заголовка и краткого содержания
+type story struct {
+	title, summary string
+}
+
+// Одна новость в пересказе разных изданий или в исправленной редакции
+var republished = [][2]story{
+	{
+		{"Центробанк сохранил ключевую ставку на уровне 16% годовых", "Совет директоров Банка России на заседании в пятницу принял решение сохранить ключевую ставку на уровне 16% годовых, говорится в сообщении регулятора."},
+		{"ЦБ сохранил ключевую ставку на уровне 16%", "Совет директоров Банка России в пятницу принял решение сохранить ключевую ставку на уровне 16% годовых, сообщил регулятор."},
+	},
+	{
+		{"В Москве открылась новая станция метро «Лефортово»", "Станция Большой кольцевой линии приняла первых пассажиров в субботу утром, сообщил мэр Москвы Сергей Собянин."},
+		{"Собянин открыл станцию метро «Лефортово» на Большой кольцевой линии", "Первых пассажиров новая станция приняла в субботу утром, сообщил мэр Москвы."},
+	},
+	{
+		{"SpaceX successfully launches Starship on its fifth test flight", "The giant rocket lifted off from Boca Chica, Texas, and the booster was caught by the launch tower arms for the first time."},
+		{"Starship fifth test flight: SpaceX catches booster with launch tower", "SpaceX's giant rocket lifted off from Boca Chica, Texas, on Sunday and the Super Heavy booster was caught by the tower arms for the first time."},
+	},
+	{
+		{"Apple unveils iPhone 16 with new camera button", "Apple on Monday introduced the iPhone 16 lineup, adding a dedicated camera control button and its Apple Intelligence features."},
+		{"Apple announces iPhone 16 lineup with camera control button", "On Monday Apple introduced the iPhone 16 and iPhone 16 Plus, which add a dedicated camera control button and support Apple Intelligence features."},
+	},
+	{
+		{"Сборная России по хоккею обыграла Финляндию со счетом 4:2", "Российские хоккеисты одержали победу над сборной Финляндии в матче Кубка Первого канала, шайбы забросили Капризов, Панарин и Кучеров."},
+		{"Россия победила Финляндию на Кубке Первого канала", "Сборная России по хоккею обыграла команду Финляндии со счетом 4:2, в составе россиян шайбы забросили Капризов, Панарин и Кучеров."},
+	},
+	{
+		{"Курс доллара на Мосбирже опустился ниже 90 рублей впервые с июня", "Курс доллара США на Московской бирже в ходе торгов во вторник опустился ниже 90 рублей впервые с 20 июня."},
+		{"Доллар опустился ниже 90 рублей впервые с июня", "В ходе торгов на Московской бирже во вторник курс доллара опустился ниже отметки 90 рублей впервые с 20 июня, следует из данных торгов."},
+	},
+	{
+		{"Go 1.22 released with range over integers and loop variable fix", "The Go team has released Go 1.22, which changes for loop variables to be per-iteration and adds range over integers."},
+		{"Go 1.22 is released", "The Go team announced the release of Go 1.22: for loop variables are now per-iteration, and for loops can now range over integers."},
+	},
+	{
+		{"Землетрясение магнитудой 6,1 произошло у берегов Японии", "Землетрясение магнитудой 6,1 произошло у восточного побережья японского острова Хонсю, угрозы цунами нет, сообщает японское метеорологическое агентство."},
+		{"У побережья Японии произошло землетрясение магнитудой 6,1", "Толчки магнитудой 6,1 зафиксированы у восточного побережья острова Хонсю, угрозы цунами нет, сообщило метеорологическое агентство Японии."},
+	},
+	// Замена одного слова в заголовке
+	{
+		{"Минфин предложил повысить НДФЛ для богатых", "Министерство финансов внесло в правительство поправки в Налоговый кодекс, предусматривающие прогрессивную шкалу налога на доходы физических лиц."},
+		{"Минфин предложил повысить НДФЛ для состоятельных граждан", "Министерство финансов внесло в правительство поправки в Налоговый кодекс, предусматривающие прогрессивную шкалу налога на доходы физических лиц."},
+	},
+	// Исправление опечатки
+	{
+		{"Курс евро на Мосбирже превысил 100 рублей", "Курс евро на Московской бирже в ходе торгов в понедельник поднялся выше 100 рублей впервые с октября."},
+		{"Курс евро на Мосбирже превысил 100 рублей", "Курс евро на Московской бирже в ходе торгов в понедельник поднялся выше 100 рублей впервые с начала октября."},
+	},
+}
+
+// Разные новости, в том числе на те же темы и с теми же словами, что и перепечатки
+var unrelated = []story{
+	{"В Петербурге из-за сильного ветра перекрыли дамбу", "Движение по комплексу защитных сооружений ограничено из-за штормового предупреждения, сообщили в ГИБДД."},
+	{"Apple unveils new MacBook Pro with M4 chip", "The new laptops feature the M4, M4 Pro and M4 Max chips and start shipping next week."},
+	{"Центробанк отозвал лицензию у московского банка", "Банк России отозвал лицензию у кредитной организации за нарушение законодательства о противодействии отмыванию доходов."},
+	{"NASA delays Artemis II crewed moon mission to 2026", "The agency said the heat shield of the Orion capsule needs further analysis before the crewed flight around the Moon."},
+	{"Сборная России по футболу сыграет с Сирией в ноябре", "Товарищеский матч пройдет в Волгограде, сообщил Российский футбольный союз."},
+	{"Курс евро на Мосбирже превысил 100 рублей", "Курс евро на Московской бирже в ходе торгов в понедельник поднялся выше 100 рублей впервые с октября."},
+	{"Rust 1.80 released with LazyCell and LazyLock", "The Rust team has released a new version of the language with lazily initialized values in the standard library."},
+	{"В Японии одобрили выброс очищенной воды с АЭС Фукусима", "Правительство Японии одобрило план сброса в океан очищенной воды с аварийной станции."},
+}
+
+func TestSimhashRepublished(t *testing.T) {
+	for _, pair := range republished {
+		a, b := simhash(pair[0].title, pair[0].summary), simhash(pair[1].title, pair[1].summary)
+		if d := storage.Distance(a, b); d > storage.ClusterDistance {
+			t.Errorf("%q и %q: расстояние %d, ожидалось не больше %d", pair[0].title, pair[1].title, d, storage.ClusterDistance)
+		}
+	}
+}
+
+func TestSimhashUnrelated(t *testing.T) {
+	// Разные новости - список несвязанных и первые новости каждой пары перепечаток
+	stories := slices.Clone(unrelated)
+	for _, pair := range republished[:len(republished)-1] {
+		stories = append(stories, pair[0])
+	}
+	for i, a := range stories {
+		for _, b := range stories[i+1:] {
+			if d := storage.Distance(simhash(a.title, a.summary), simhash(b.title, b.summary)); d <= storage.ClusterDistance {
+				t.Errorf("%q и %q: расстояние %d, ожидалось больше %d", a.title, b.title, d, storage.ClusterDistance)
+			}
+		}
+	}
+}
+
+func TestSimhashFeatures(t *testing.T) {
+	// Формы слова, регистр, знаки препинания и служебные слова не меняют отпечаток
+	if a, b := simhash("Банка России сохранил ставку", ""), simhash("банка россии: сохранила ставки", "и в на"); a != b {
+		t.Errorf("отпечатки %x и %x, ожидались равные", a, b)
+	}
+	// Текст без значимых слов - без отпечатка
+	for _, text := range []string{"", "и в на", strings.Repeat(" ", 5), "1 2 ок"} {
+		if fp := simhash(text, text); fp != 0 {
+			t.Errorf("simhash(%q) = %x, ожидалось 0", text, fp)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/antibaloo/sf-final-project/internal/config"
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/antibaloo/sf-final-project/internal/storage/memory"
+	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+)
+
+// Создает хранилище того типа, который указан в конфигурации
+func NewStore(cfg *config.Config) (storage.Store, error) {
+	switch cfg.Storage() {
+	case config.StoragePostgres:
+		return postgres.NewStore(cfg.ConString())
+	case config.StorageMemory:
+		return memory.NewStore(), nil
+	default:
+		return nil, fmt.Errorf("неизвестный тип хранилища: %s", cfg.Storage())
+	}
+}
@@ -0,0 +1,589 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Структура хранилища в оперативной памяти, повторяет поведение хранилища PostgreSQL
+type Store struct {
+	mu            sync.RWMutex
+	news          map[int]storage.NewsShortDetailed // Новости по идентификатору
+	links         map[string]int                    // Индекс канонических ссылок на новости
+	comments      map[int][]storage.Comment         // Комментарии по идентификатору новости
+	dictionary    []string                          // Словарь запрещенных слов
+	sources       map[int]storage.Source            // Источники новостей по идентификатору
+	revisions     map[int][]storage.Revision        // Предыдущие версии новостей по идентификатору новости
+	lastNewsId    int                               // Последний выданный идентификатор новости
+	lastCommentId int                               // Последний выданный идентификатор комментария
+	lastSourceId  int                               // Последний выданный идентификатор источника
+	lastRevision  int                               // Последний выданный идентификатор версии новости
+}
+
+// Конструктор хранилища
+func NewStore() *Store {
+	return &Store{
+		news:       map[int]storage.NewsShortDetailed{},
+		links:      map[string]int{},
+		comments:   map[int][]storage.Comment{},
+		dictionary: []string{"xxx", "yyy", "zzz"}, // Те же слова, что добавляет миграция 20241023100328_create.sql
+		sources:    map[int]storage.Source{},
+		revisions:  map[int][]storage.Revision{},
+	}
+}
+
+// Метод добавления новости. Если новость с той же канонической ссылкой уже есть, она обновляется
+// при изменении заголовка или текста в том же источнике.
+func (s *Store) AddNews(ctx context.Context, news storage.NewsShortDetailed) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(news)
+}
+
+// Метод добавления пакета новостей, пакет добавляется под одной блокировкой
+func (s *Store) AddNewsBatch(ctx context.Context, news []storage.NewsShortDetailed) ([]storage.BatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]storage.BatchResult, len(news))
+	for i, n := range news {
+		result, err := s.add(n)
+		if err != nil {
+			results[i] = storage.BatchResult{Result: storage.NewsFailed, Err: err}
+			continue
+		}
+		results[i].Result = result
+	}
+	return results, nil
+}
+
+// Добавляет новость, вызывается под блокировкой
+func (s *Store) add(news storage.NewsShortDetailed) (string, error) {
+	if news.Title == "" {
+		return "", fmt.Errorf("не указан заголовок новости")
+	}
+	link := storage.CanonicalLink(news.Link)
+	if id, exist := s.links[link]; exist {
+		return s.update(id, news), nil
+	}
+	// Новость, сохраненная раньше по другой своей ссылке, - та же новость
+	for _, alias := range news.Aliases {
+		if id, exist := s.links[storage.CanonicalLink(alias)]; exist {
+			return s.update(id, news), nil
+		}
+	}
+	// Новость может ссылаться только на существующий источник, сведения об источнике берутся при выборке
+	if news.Source != nil {
+		if _, exist := s.sources[news.Source.Id]; !exist {
+			return "", fmt.Errorf("источник с идентификатором %d не найден", news.Source.Id)
+		}
+		news.Source = &storage.NewsSource{Id: news.Source.Id}
+	}
+	// Вложения копируем, чтобы вызывающий код не мог изменить сохраненную новость
+	news.Media = slices.Clone(news.Media)
+	news.Aliases = nil
+	news.ClusterSize, news.Alternates = 0, nil
+	news.UpdatedAt, news.Revisions = 0, nil
+	s.lastNewsId++
+	news.Id = s.lastNewsId
+	news.ClusterId = s.cluster(news)
+	s.news[news.Id] = news
+	s.links[link] = news.Id
+	return storage.NewsInserted, nil
+}
+
+// Обновляет заголовок и текст сохраненной новости, если они изменились в том же источнике, и сохраняет
+// предыдущую версию в истории. Возвращает результат добавления, вызывается под блокировкой.
+func (s *Store) update(id int, news storage.NewsShortDetailed) string {
+	old := s.news[id]
+	// Новость, сохраненная до появления источников, становится новостью первого источника, из которого
+	// она пришла снова
+	if sourceId(old) == 0 && sourceId(news) != 0 {
+		old.Source = &storage.NewsSource{Id: news.Source.Id}
+		s.news[id] = old
+	}
+	// Та же новость из другого источника не обновляется, иначе версии источников сменяли бы друг друга
+	if storage.ContentHash(old) == storage.ContentHash(news) || sourceId(old) != sourceId(news) {
+		return storage.NewsUnchanged
+	}
+	s.lastRevision++
+	now := time.Now().Unix()
+	s.revisions[id] = append(s.revisions[id], storage.Revision{
+		Id:         s.lastRevision,
+		Title:      old.Title,
+		Content:    old.Content,
+		Body:       old.Body,
+		ReplacedAt: now,
+	})
+	old.Title, old.Content, old.Body = news.Title, news.Content, news.Body
+	old.Fingerprint, old.UpdatedAt = news.Fingerprint, now
+	s.news[id] = old
+	return storage.NewsUpdated
+}
+
+// Возвращает идентификатор источника новости, 0 - новость без источника
+func sourceId(news storage.NewsShortDetailed) int {
+	if news.Source == nil {
+		return 0
+	}
+	return news.Source.Id
+}
+
+// Возвращает группу похожих новостей для добавляемой новости: группу новости с ближайшим отпечатком среди новостей,
+// опубликованных в пределах storage.ClusterWindow, или идентификатор самой новости, если похожих нет.
+// Вызывается под блокировкой.
+func (s *Store) cluster(news storage.NewsShortDetailed) int {
+	cluster, best, bestId := news.Id, storage.ClusterDistance+1, 0
+	if news.Fingerprint == 0 {
+		return cluster
+	}
+	for _, n := range s.news {
+		if n.Fingerprint == 0 || n.PubTime < news.PubTime-storage.ClusterWindow || n.PubTime > news.PubTime+storage.ClusterWindow {
+			continue
+		}
+		// При равном расстоянии выбирается более ранняя новость, как в хранилище PostgreSQL
+		if d := storage.Distance(n.Fingerprint, news.Fingerprint); d < best || d == best && n.Id < bestId {
+			cluster, best, bestId = n.ClusterId, d, n.Id
+		}
+	}
+	return cluster
+}
+
+// Метод получения списка новостей по параметрам выборки и общего количества подходящих новостей
+func (s *Store) News(ctx context.Context, q storage.NewsQuery) ([]storage.NewsShortDetailed, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	var news []storage.NewsShortDetailed
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := s.filter(q)
+	// Сортируем по заданному полю, при равенстве значений порядок задает идентификатор
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if q.Asc {
+			a, b = b, a
+		}
+		switch {
+		case q.Sort == storage.SortPubTime:
+			return keyLess(b.news.PubTime, b.news.Id, a.news.PubTime, a.news.Id)
+		case q.Sort == storage.SortId || !q.FullText:
+			return a.news.Id > b.news.Id
+		case a.rank != b.rank:
+			return a.rank > b.rank
+		default:
+			return a.news.Id > b.news.Id
+		}
+	})
+	if q.Collapse {
+		found = collapse(found)
+	}
+	count := len(found)
+	offset := q.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= count {
+		return news, count, nil
+	}
+	end := count
+	if q.Limit >= 0 && offset+q.Limit < end {
+		end = offset + q.Limit
+	}
+	for _, h := range found[offset:end] {
+		news = append(news, s.result(h, q))
+	}
+	return news, count, nil
+}
+
+// Метод получения страницы новостей относительно курсора, новости отдаются по убыванию (pub_time, id)
+func (s *Store) NewsPage(ctx context.Context, cursor *storage.Cursor, q storage.NewsQuery) ([]storage.NewsShortDetailed, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	var news []storage.NewsShortDetailed
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Отбираем новости по параметрам выборки и сортируем по убыванию (pub_time, id)
+	all := s.filter(q)
+	sort.Slice(all, func(i, j int) bool {
+		return keyLess(all[j].news.PubTime, all[j].news.Id, all[i].news.PubTime, all[i].news.Id)
+	})
+	// Группа представлена самой новой новостью независимо от положения курсора
+	if q.Collapse {
+		all = collapse(all)
+	}
+	// Оставляем новости по нужную сторону от курсора
+	var found []hit
+	for _, h := range all {
+		if cursor != nil && cursor.Before != keyLess(cursor.PubTime, cursor.Id, h.news.PubTime, h.news.Id) {
+			continue
+		}
+		if cursor != nil && h.news.PubTime == cursor.PubTime && h.news.Id == cursor.Id {
+			continue
+		}
+		found = append(found, h)
+	}
+	limit := q.Limit
+	if limit < 0 {
+		limit = len(found)
+	}
+	more := len(found) > limit
+	// Страница перед курсором - ближайшие к нему новости, т.е. последние в отсортированном списке
+	if cursor != nil && cursor.Before && more {
+		found = found[len(found)-limit:]
+	} else if more {
+		found = found[:limit]
+	}
+	for _, h := range found {
+		news = append(news, s.result(h, q))
+	}
+	return news, more, nil
+}
+
+// Новость, подходящая под параметры выборки
+type hit struct {
+	news  storage.NewsShortDetailed
+	rank  int      // Релевантность при полнотекстовом поиске
+	stems []string // Основы слов полнотекстового поиска
+}
+
+// Возвращает новость для ответа, при необходимости с фрагментом текста с подсветкой и остальными
+// новостями группы. Вызывается под блокировкой.
+func (s *Store) result(h hit, q storage.NewsQuery) storage.NewsShortDetailed {
+	if q.Highlight && len(h.stems) > 0 {
+		h.news.Snippet = snippet(h.news.Content, h.stems)
+	}
+	// Полный текст отдается только в детальной новости
+	h.news.Body = ""
+	if q.Collapse {
+		h.news.ClusterSize, h.news.Alternates = s.alternates(h.news)
+	}
+	return h.news
+}
+
+// Оставляет из каждой группы похожих новостей первую в списке
+func collapse(found []hit) []hit {
+	seen := map[int]bool{}
+	collapsed := found[:0]
+	for _, h := range found {
+		if seen[h.news.ClusterId] {
+			continue
+		}
+		seen[h.news.ClusterId] = true
+		collapsed = append(collapsed, h)
+	}
+	return collapsed
+}
+
+// Возвращает размер группы новости и остальные новости группы по убыванию времени публикации.
+// Вызывается под блокировкой.
+func (s *Store) alternates(news storage.NewsShortDetailed) (int, []storage.Alternate) {
+	var others []storage.NewsShortDetailed
+	for _, n := range s.news {
+		if n.ClusterId == news.ClusterId && n.Id != news.Id {
+			others = append(others, s.output(n))
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return keyLess(others[j].PubTime, others[j].Id, others[i].PubTime, others[i].Id)
+	})
+	var alternates []storage.Alternate
+	for _, n := range others {
+		alternates = append(alternates, storage.Alternate{Id: n.Id, Title: n.Title, Link: n.Link, Source: n.Source})
+	}
+	return len(others) + 1, alternates
+}
+
+// Отбирает новости по условиям выборки, вызывается под блокировкой
+func (s *Store) filter(q storage.NewsQuery) []hit {
+	var stems []string
+	if q.FullText {
+		stems = stemAll(words(q.Search))
+	}
+	search := strings.ToLower(q.Search)
+	found := make([]hit, 0, len(s.news))
+	for _, n := range s.news {
+		if q.From != 0 && n.PubTime < q.From || q.To != 0 && n.PubTime > q.To {
+			continue
+		}
+		if q.Source != "" && storage.Host(n.Link) != q.Source {
+			continue
+		}
+		if q.SourceId != 0 && (n.Source == nil || n.Source.Id != q.SourceId) {
+			continue
+		}
+		if q.SourceURL != "" && (n.Source == nil || s.sources[n.Source.Id].URL != q.SourceURL) {
+			continue
+		}
+		h := hit{news: s.output(n), stems: stems}
+		switch {
+		case len(stems) > 0:
+			if h.rank = rank(n, stems); h.rank == 0 {
+				continue
+			}
+		case q.FullText && q.Search != "":
+			// В запросе нет ни одного слова
+			continue
+		case !strings.Contains(strings.ToLower(n.Title), search):
+			continue
+		}
+		found = append(found, h)
+	}
+	return found
+}
+
+// Сравнивает ключи сортировки новостей (pub_time, id)
+func keyLess(pubTime1 int64, id1 int, pubTime2 int64, id2 int) bool {
+	if pubTime1 != pubTime2 {
+		return pubTime1 < pubTime2
+	}
+	return id1 < id2
+}
+
+// Метод получения детальной новости по идентификатору, вместе с полным текстом
+func (s *Store) NewsByID(ctx context.Context, id int) (storage.NewsShortDetailed, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.NewsShortDetailed{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	news, exist := s.news[id]
+	if !exist {
+		return storage.NewsShortDetailed{}, storage.ErrNotFound
+	}
+	return s.output(news), nil
+}
+
+// Готовит сохраненную новость к выдаче: дополняет сведениями об источнике, копирует вложения и скрывает
+// отпечаток, вызывается под блокировкой
+func (s *Store) output(n storage.NewsShortDetailed) storage.NewsShortDetailed {
+	n.Media = slices.Clone(n.Media)
+	n.Fingerprint = 0
+	if n.Source == nil {
+		return n
+	}
+	src := s.sources[n.Source.Id]
+	n.Source = &storage.NewsSource{Id: src.Id, Title: src.Title, SiteURL: src.SiteURL}
+	return n
+}
+
+// Метод получения предыдущих версий новости, от последней к первой
+func (s *Store) NewsRevisions(ctx context.Context, id int) ([]storage.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var revisions []storage.Revision
+	for i := len(s.revisions[id]) - 1; i >= 0; i-- {
+		revisions = append(revisions, s.revisions[id][i])
+	}
+	return revisions, nil
+}
+
+// Метод добавления коментария
+func (s *Store) AddComment(ctx context.Context, comment storage.Comment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if comment.Content == "" {
+		return fmt.Errorf("не указан текст комментария")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Комментарий может быть добавлен только к существующей новости
+	if _, exist := s.news[comment.NewsId]; !exist {
+		return fmt.Errorf("новость с идентификатором %d не найдена", comment.NewsId)
+	}
+	s.lastCommentId++
+	comment.Id = s.lastCommentId
+	comment.CreatedAt = time.Now().Unix()
+	comment.UpdatedAt = comment.CreatedAt
+	s.comments[comment.NewsId] = append(s.comments[comment.NewsId], comment)
+	return nil
+}
+
+// Метод получение списка комментариев к новости
+func (s *Store) CommentsByNewsId(ctx context.Context, id int) ([]storage.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var comments []storage.Comment
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	comments = append(comments, s.comments[id]...)
+	return comments, nil
+}
+
+// Метод получения словаря запрещенных слов
+func (s *Store) Dictionary(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	words := make([]string, len(s.dictionary))
+	copy(words, s.dictionary)
+	return words, nil
+}
+
+// Метод добавления запрещенного слова в словарь
+func (s *Store) AddWord2Dictionary(ctx context.Context, word string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range s.dictionary {
+		if w == word {
+			return fmt.Errorf("слово %q уже есть в словаре", word)
+		}
+	}
+	s.dictionary = append(s.dictionary, word)
+	return nil
+}
+
+// Метод получения списка источников новостей
+func (s *Store) Sources(ctx context.Context) ([]storage.Source, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var sources []storage.Source
+	for _, src := range s.sources {
+		sources = append(sources, src)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Id < sources[j].Id })
+	return sources, nil
+}
+
+// Метод получения источника новостей по идентификатору
+func (s *Store) SourceByID(ctx context.Context, id int) (storage.Source, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Source{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	src, exist := s.sources[id]
+	if !exist {
+		return storage.Source{}, storage.ErrNotFound
+	}
+	return src, nil
+}
+
+// Ищет источник с заданным адресом, кроме источника с идентификатором except
+func (s *Store) sourceByURL(url string, except int) (storage.Source, bool) {
+	for _, src := range s.sources {
+		if src.URL == url && src.Id != except {
+			return src, true
+		}
+	}
+	return storage.Source{}, false
+}
+
+// Метод добавления источника новостей, возвращает идентификатор нового источника
+func (s *Store) AddSource(ctx context.Context, src storage.Source) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if src.URL == "" {
+		return 0, fmt.Errorf("не указан адрес источника")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exist := s.sourceByURL(src.URL, 0); exist {
+		return 0, storage.ErrDuplicateSource
+	}
+	s.lastSourceId++
+	s.sources[s.lastSourceId] = storage.Source{
+		Id:           s.lastSourceId,
+		Title:        src.Title,
+		Enabled:      src.Enabled,
+		PollInterval: src.PollInterval,
+		Category:     src.Category,
+		FeedState:    storage.FeedState{URL: src.URL, Healthy: true},
+	}
+	return s.lastSourceId, nil
+}
+
+// Метод изменения адреса, названия, признака включения, периода опроса и категории источника.
+// При смене адреса состояние опроса сбрасывается.
+func (s *Store) UpdateSource(ctx context.Context, src storage.Source) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if src.URL == "" {
+		return fmt.Errorf("не указан адрес источника")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, exist := s.sources[src.Id]
+	if !exist {
+		return storage.ErrNotFound
+	}
+	if _, exist := s.sourceByURL(src.URL, src.Id); exist {
+		return storage.ErrDuplicateSource
+	}
+	if old.URL != src.URL {
+		old.FeedState = storage.FeedState{URL: src.URL, Healthy: true}
+	}
+	old.Title, old.Enabled, old.PollInterval, old.Category = src.Title, src.Enabled, src.PollInterval, src.Category
+	s.sources[src.Id] = old
+	return nil
+}
+
+// Метод удаления источника новостей
+func (s *Store) DeleteSource(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exist := s.sources[id]; !exist {
+		return storage.ErrNotFound
+	}
+	delete(s.sources, id)
+	// Вместе с источником удаляются его новости и комментарии к ним
+	for newsId, n := range s.news {
+		if n.Source != nil && n.Source.Id == id {
+			delete(s.news, newsId)
+			delete(s.links, storage.CanonicalLink(n.Link))
+			delete(s.comments, newsId)
+			delete(s.revisions, newsId)
+		}
+	}
+	return nil
+}
+
+// Метод сохранения состояния опроса источника с заданным адресом
+func (s *Store) SaveFeedState(ctx context.Context, state storage.FeedState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	src, exist := s.sourceByURL(state.URL, 0)
+	if !exist {
+		return storage.ErrNotFound
+	}
+	src.FeedState = state
+	s.sources[src.Id] = src
+	return nil
+}
+
+// Метод закрытия хранилища, хранилищу в памяти освобождать нечего
+func (s *Store) Close() {}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/antibaloo/sf-final-project/internal/storage/memory"
+	"github.com/antibaloo/sf-final-project/internal/storage/storagetest"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return memory.NewStore()
+	})
+}
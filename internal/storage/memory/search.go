@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Сколько слов текста попадает во фрагмент с подсветкой
+const snippetWords = 25
+
+// Релевантность новости для полнотекстового поиска, упрощенный аналог поиска PostgreSQL:
+// новость подходит, если каждое слово запроса найдено в заголовке, кратком содержании или полном тексте,
+// совпадение в заголовке весит вдвое больше совпадения в кратком содержании, а оно - вдвое больше совпадения
+// в полном тексте. 0 - новость не подходит.
+func rank(n storage.NewsShortDetailed, stems []string) int {
+	title, content, body := words(n.Title), words(n.Content), words(htmlText(n.Body))
+	rank := 0
+	for _, stem := range stems {
+		titleHits, contentHits, bodyHits := matches(title, stem), matches(content, stem), matches(body, stem)
+		if titleHits+contentHits+bodyHits == 0 {
+			return 0
+		}
+		rank += 4*titleHits + 2*contentHits + bodyHits
+	}
+	return rank
+}
+
+// Возвращает текст html без тэгов, как его видит разборщик tsvector
+func htmlText(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:start])
+		b.WriteByte(' ')
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			break
+		}
+		s = s[start+end+1:]
+	}
+	return html.UnescapeString(b.String())
+}
+
+// Разбивает текст на слова в нижнем регистре
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Грубое приведение слова к основе: у длинных слов отбрасываем окончание
+func stem(word string) string {
+	n := utf8.RuneCountInString(word)
+	if n <= 5 {
+		return word
+	}
+	return string([]rune(word)[:n-2])
+}
+
+func stemAll(words []string) []string {
+	stems := make([]string, 0, len(words))
+	for _, w := range words {
+		stems = append(stems, stem(w))
+	}
+	return stems
+}
+
+// Считает слова, начинающиеся с основы
+func matches(words []string, stem string) int {
+	count := 0
+	for _, w := range words {
+		if strings.HasPrefix(w, stem) {
+			count++
+		}
+	}
+	return count
+}
+
+// Проверяет, начинается ли хотя бы одно слово с одной из основ
+func matchesAny(words []string, stems []string) bool {
+	for _, stem := range stems {
+		if matches(words, stem) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Возвращает фрагмент текста вокруг первого найденного слова, найденные слова обрамляются <mark></mark>
+func snippet(content string, stems []string) string {
+	fields := strings.Fields(content)
+	first := -1
+	for i, f := range fields {
+		if !matchesAny(words(f), stems) {
+			continue
+		}
+		fields[i] = "<mark>" + f + "</mark>"
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+	start := first - snippetWords/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(fields) {
+		end = len(fields)
+	}
+	return strings.Join(fields[start:end], " ")
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE news ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', content), 'B') ||
+    setweight(to_tsvector('english', content), 'B')
+) STORED;
+
+CREATE INDEX news_search_vector_idx ON news USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS news_search_vector_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sources(
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL UNIQUE CHECK(url <> ''),
+    etag TEXT NOT NULL DEFAULT '',
+    last_modified TEXT NOT NULL DEFAULT ''
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sources;
+-- +goose StatementEnd
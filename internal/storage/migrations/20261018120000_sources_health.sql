@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sources
+    ADD COLUMN failures INT NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_error_at BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN healthy BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS failures,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS last_error_at,
+    DROP COLUMN IF EXISTS healthy;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sources
+    ADD COLUMN title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN poll_interval INT NOT NULL DEFAULT 0 CHECK(poll_interval >= 0),
+    ADD COLUMN last_status TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_fetch_at BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sources
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS enabled,
+    DROP COLUMN IF EXISTS poll_interval,
+    DROP COLUMN IF EXISTS last_status,
+    DROP COLUMN IF EXISTS last_fetch_at;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sources ADD COLUMN site_url TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE news
+    ADD COLUMN source_id INT,
+    ADD CONSTRAINT fk_news_source_id
+        FOREIGN KEY (source_id)
+            REFERENCES sources (id)
+            ON DELETE CASCADE;
+
+CREATE INDEX news_source_id_idx ON news (source_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS news_source_id_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS source_id;
+ALTER TABLE sources DROP COLUMN IF EXISTS site_url;
+-- +goose StatementEnd
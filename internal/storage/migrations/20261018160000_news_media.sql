@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE news_media(
+    id SERIAL PRIMARY KEY,
+    news_id INT NOT NULL,
+    url TEXT NOT NULL CHECK(url <> ''),
+    type TEXT NOT NULL DEFAULT '',
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    CONSTRAINT fk_news_media_news_id
+        FOREIGN KEY (news_id)
+            REFERENCES news (id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX news_media_news_id_idx ON news_media (news_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS news_media;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Повторы новостей определяются по канонической ссылке. Для уже сохраненных новостей ей временно считается исходная
+-- ссылка, к виду storage.CanonicalLink ее приводит сервис новостей при запуске (postgres.Store.CanonicalizeLinks,
+-- миграция 20261018220000_backfills.sql)
+ALTER TABLE news ADD COLUMN canonical_link TEXT;
+UPDATE news SET canonical_link = link;
+ALTER TABLE news
+    ALTER COLUMN canonical_link SET NOT NULL,
+    ADD CONSTRAINT news_canonical_link_key UNIQUE (canonical_link),
+    DROP CONSTRAINT IF EXISTS news_link_key;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE news ADD CONSTRAINT news_link_key UNIQUE (link);
+ALTER TABLE news DROP COLUMN IF EXISTS canonical_link;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Пустой cluster_id означает, что новость - первая в своей группе
+ALTER TABLE news
+    ADD COLUMN fingerprint BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN cluster_id INT;
+
+CREATE INDEX news_cluster_id_idx ON news (cluster_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS news_cluster_id_idx;
+ALTER TABLE news
+    DROP COLUMN IF EXISTS cluster_id,
+    DROP COLUMN IF EXISTS fingerprint;
+-- +goose StatementEnd
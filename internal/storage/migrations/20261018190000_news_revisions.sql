@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Хэш заголовка и текста вычисляется так же, как storage.ContentHash
+ALTER TABLE news
+    ADD COLUMN content_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN updated_at INT NOT NULL DEFAULT 0;
+UPDATE news SET content_hash = encode(sha256(convert_to(title || chr(31) || content || chr(31) || body, 'UTF8')), 'hex');
+
+CREATE TABLE news_revisions(
+    id SERIAL PRIMARY KEY,
+    news_id INT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    replaced_at INT NOT NULL,
+    CONSTRAINT fk_news_revisions_news_id
+        FOREIGN KEY (news_id)
+            REFERENCES news (id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX news_revisions_news_id_idx ON news_revisions (news_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS news_revisions;
+ALTER TABLE news
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS content_hash;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Выражение генерируемого столбца не меняется, поэтому столбец и индекс создаются заново. Тэги и сущности html
+-- полного текста разборщик tsvector распознает отдельно и в индекс не включает.
+DROP INDEX IF EXISTS news_search_vector_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE news ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', content), 'B') ||
+    setweight(to_tsvector('english', content), 'B') ||
+    setweight(to_tsvector('russian', body), 'C') ||
+    setweight(to_tsvector('english', body), 'C')
+) STORED;
+
+CREATE INDEX news_search_vector_idx ON news USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS news_search_vector_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE news ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', content), 'B') ||
+    setweight(to_tsvector('english', content), 'B')
+) STORED;
+
+CREATE INDEX news_search_vector_idx ON news USING GIN (search_vector);
+-- +goose StatementEnd
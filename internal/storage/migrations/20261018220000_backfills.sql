@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Отметки о выполненных сервисами переносах данных, которые нельзя выразить на SQL
+CREATE TABLE IF NOT EXISTS backfills (
+    name TEXT PRIMARY KEY,
+    done_at INT NOT NULL DEFAULT 0
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS backfills;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Отпечатки прежнего алгоритма (пары слов) несравнимы с новыми, новости с ними остаются в своих группах
+UPDATE news SET fingerprint = 0 WHERE fingerprint <> 0;
+
+-- Кандидаты в группы похожих новостей выбираются по времени публикации среди новостей с отпечатком
+CREATE INDEX news_fingerprint_pub_time_idx ON news (pub_time) WHERE fingerprint <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS news_fingerprint_pub_time_idx;
+-- +goose StatementEnd
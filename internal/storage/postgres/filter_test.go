@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+)
+
+// Строка поиска не должна попадать в текст запроса, только в параметры
+func TestFilterNewsHostile(t *testing.T) {
+	tests := []struct {
+		search string
+		arg    string
+	}{
+		{"%", `%\%%`},
+		{"snake_case", `%snake\_case%`},
+		{`C:\Go`, `%c:\\go%`},
+		{"O'Reilly", "%o'reilly%"},
+		{"' OR '1'='1", "%' or '1'='1%"},
+		{"'; DROP TABLE news; --", "%'; drop table news; --%"},
+	}
+	for _, tt := range tests {
+		for _, fullText := range []bool{false, true} {
+			f := filterNews(storage.NewsQuery{Search: tt.search, FullText: fullText, Source: tt.search, SourceURL: tt.search})
+			if strings.Contains(f.condition(), tt.search) {
+				t.Errorf("filterNews(%q): строка поиска в тексте запроса: %s", tt.search, f.condition())
+			}
+			if len(f.args) != 3 || f.args[1] != tt.search || f.args[2] != tt.search {
+				t.Errorf("filterNews(%q): параметры %q", tt.search, f.args)
+			}
+			if !fullText && f.args[0] != tt.arg {
+				t.Errorf("filterNews(%q): шаблон LIKE %q, ожидалось %q", tt.search, f.args[0], tt.arg)
+			}
+		}
+	}
+}
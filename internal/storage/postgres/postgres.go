@@ -2,9 +2,15 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -22,93 +28,820 @@ func NewStore(conStr string) (*Store, error) {
 	return &Store{Pool: db}, nil
 }
 
-// Метод добавления новости
-func (s *Store) AddNews(news storage.NewsShortDetailed) error {
-	_, err := s.Pool.Exec(
-		context.Background(),
-		"INSERT INTO news(title, content, pub_time, link) VALUES ($1, $2, $3, $4)",
-		news.Title,
-		news.Content,
-		news.PubTime,
-		news.Link,
+// Метод добавления новости вместе с ее изображениями и вложениями. Если новость с той же канонической
+// ссылкой уже есть, она обновляется при изменении заголовка или текста в том же источнике.
+func (s *Store) AddNews(ctx context.Context, news storage.NewsShortDetailed) (string, error) {
+	results, err := s.addNewsBatch(ctx, []storage.NewsShortDetailed{news})
+	if err != nil {
+		return "", err
+	}
+	if results[0].Err != nil {
+		return "", results[0].Err
+	}
+	return results[0].Result, nil
+}
+
+// Метод добавления пакета новостей. Пакет записывается одной транзакцией за несколько обращений к БД,
+// если это не удалось, новости добавляются по одной, чтобы ошибка одной новости не мешала остальным.
+func (s *Store) AddNewsBatch(ctx context.Context, news []storage.NewsShortDetailed) ([]storage.BatchResult, error) {
+	results, err := s.addNewsBatch(ctx, news)
+	if err == nil {
+		return results, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	results = make([]storage.BatchResult, len(news))
+	for i, n := range news {
+		result, err := s.AddNews(ctx, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			results[i] = storage.BatchResult{Result: storage.NewsFailed, Err: err}
+			continue
+		}
+		results[i].Result = result
+	}
+	return results, nil
+}
+
+// Название переноса данных, приводящего ссылки сохраненных новостей к каноническому виду
+const canonicalLinkBackfill = "canonical_link"
+
+// Метод приводит канонические ссылки новостей, сохраненных до их появления, к виду storage.CanonicalLink.
+// Выполняется один раз, выполнение отмечается в таблице backfills. Если каноническая ссылка уже занята
+// другой новостью, у более поздней новости остается исходная ссылка. Возвращает количество измененных новостей.
+func (s *Store) CanonicalizeLinks(ctx context.Context) (int, error) {
+	var updated int
+	err := pgx.BeginFunc(ctx, s.Pool, func(tx pgx.Tx) error {
+		// Отметка блокирует одновременный перенос другим экземпляром сервиса до конца транзакции
+		tag, err := tx.Exec(
+			ctx,
+			`INSERT INTO backfills (name, done_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+			canonicalLinkBackfill,
+			time.Now().Unix(),
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		// Миграция скопировала в canonical_link исходную ссылку, новости, добавленные после нее, уже канонические
+		rows, err := tx.Query(ctx, `SELECT id, link FROM news WHERE canonical_link = link ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		var (
+			ids   []int
+			links []string
+			seen  = map[string]bool{}
+		)
+		for rows.Next() {
+			var (
+				id   int
+				link string
+			)
+			if err := rows.Scan(&id, &link); err != nil {
+				rows.Close()
+				return err
+			}
+			canonical := storage.CanonicalLink(link)
+			// Из новостей с одной канонической ссылкой ее получает первая добавленная
+			if canonical == link || seen[canonical] {
+				continue
+			}
+			seen[canonical] = true
+			ids = append(ids, id)
+			links = append(links, canonical)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		// Новости, каноническая ссылка которых уже занята, не меняются
+		tag, err = tx.Exec(
+			ctx,
+			`UPDATE news SET canonical_link = c.link
+			FROM unnest($1::int[], $2::text[]) AS c(id, link)
+			WHERE news.id = c.id AND NOT EXISTS (SELECT 1 FROM news n WHERE n.canonical_link = c.link)`,
+			ids,
+			links,
+		)
+		if err != nil {
+			return err
+		}
+		updated = int(tag.RowsAffected())
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// Сохраненная новость, с которой совпадает каноническая ссылка добавляемой новости
+type storedNews struct {
+	id       int
+	sourceId *int
+	hash     string
+}
+
+// Новость, к группе которой может быть отнесена добавляемая новость. Для новости, добавляемой тем же
+// пакетом, группа еще неизвестна и определяется при вставке по канонической ссылке.
+type clusterCandidate struct {
+	cluster     *int
+	link        string
+	fingerprint uint64
+	pubTime     int64
+}
+
+// Добавляет пакет новостей в одной транзакции: сохраненные новости и кандидаты в группы выбираются двумя
+// запросами, вставки и обновления отправляются одним пакетом. Ошибка любого запроса отменяет весь пакет.
+func (s *Store) addNewsBatch(ctx context.Context, news []storage.NewsShortDetailed) ([]storage.BatchResult, error) {
+	results := make([]storage.BatchResult, len(news))
+	links := make([]string, len(news))
+	for i, n := range news {
+		links[i] = storage.CanonicalLink(n.Link)
+	}
+	// Сохраненные новости ищутся и по другим ссылкам добавляемых новостей
+	lookup := slices.Clone(links)
+	for _, n := range news {
+		for _, alias := range n.Aliases {
+			lookup = append(lookup, storage.CanonicalLink(alias))
+		}
+	}
+	err := pgx.BeginFunc(ctx, s.Pool, func(tx pgx.Tx) error {
+		stored, err := findStoredNews(ctx, tx, lookup)
+		if err != nil {
+			return err
+		}
+		// Новость, сохраненная раньше по другой своей ссылке, - та же новость и обновляется по ней
+		for i, n := range news {
+			if _, exist := stored[links[i]]; exist {
+				continue
+			}
+			for _, alias := range n.Aliases {
+				if _, exist := stored[storage.CanonicalLink(alias)]; exist {
+					links[i] = storage.CanonicalLink(alias)
+					break
+				}
+			}
+		}
+		// Время публикации и отпечатки новых новостей с отпечатком - по ним выбираются кандидаты в группы
+		var pubTimes, fingerprints []int64
+		for i, n := range news {
+			if _, exist := stored[links[i]]; !exist && n.Fingerprint != 0 {
+				pubTimes = append(pubTimes, n.PubTime)
+				fingerprints = append(fingerprints, int64(n.Fingerprint))
+			}
+		}
+		candidates, err := findClusterCandidates(ctx, tx, pubTimes, fingerprints)
+		if err != nil {
+			return err
+		}
+		var (
+			batch   = &pgx.Batch{}
+			queued  []int               // Индексы новостей в порядке запросов пакета
+			pending = map[string]bool{} // Канонические ссылки новостей, вставляемых пакетом
+			now     = time.Now().Unix()
+		)
+		for i, n := range news {
+			if n.Title == "" {
+				results[i] = storage.BatchResult{Result: storage.NewsFailed, Err: fmt.Errorf("не указан заголовок новости")}
+				continue
+			}
+			// Новость без источника хранится с пустым source_id
+			var sourceId *int
+			if n.Source != nil {
+				sourceId = &n.Source.Id
+			}
+			hash := storage.ContentHash(n)
+			if old, exist := stored[links[i]]; exist {
+				// Новость, сохраненная до появления источников, становится новостью первого источника, из которого
+				// она пришла снова
+				legacy := old.sourceId == nil && sourceId != nil
+				// Та же новость из другого источника не обновляется, иначе версии источников сменяли бы друг друга
+				if !legacy && !sameSource(old.sourceId, sourceId) {
+					results[i].Result = storage.NewsUnchanged
+					continue
+				}
+				results[i].Result = storage.NewsUnchanged
+				switch {
+				case old.hash != hash:
+					// Текущая версия сохраняется в истории, запрос истории видит строку новости до обновления
+					batch.Queue(
+						`WITH revision AS (
+							INSERT INTO news_revisions (news_id, title, content, body, replaced_at)
+							SELECT id, title, content, body, $7 FROM news WHERE id = $1
+						)
+						UPDATE news SET title = $2, content = $3, body = $4, content_hash = $5, fingerprint = $6, updated_at = $7,
+							source_id = $8
+						WHERE id = $1`,
+						old.id,
+						n.Title,
+						n.Content,
+						n.Body,
+						hash,
+						int64(n.Fingerprint),
+						now,
+						sourceId,
+					)
+					results[i].Result = storage.NewsUpdated
+				case legacy:
+					batch.Queue(`UPDATE news SET source_id = $2 WHERE id = $1`, old.id, sourceId)
+				default:
+					continue
+				}
+				queued = append(queued, i)
+				old.hash, old.sourceId = hash, sourceId
+				stored[links[i]] = old
+				continue
+			}
+			if pending[links[i]] {
+				results[i].Result = storage.NewsUnchanged
+				continue
+			}
+			cluster := nearestCluster(candidates, n)
+			var (
+				clusterId   *int
+				clusterLink *string
+			)
+			if cluster != nil {
+				clusterId = cluster.cluster
+				if clusterId == nil {
+					clusterLink = &cluster.link
+				}
+			}
+			media := mediaColumns(n.Media)
+			batch.Queue(
+				`WITH inserted AS (
+					INSERT INTO news(title, content, pub_time, link, source_id, body, canonical_link, fingerprint, cluster_id, content_hash)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::int, (SELECT `+newsCluster+` FROM news WHERE canonical_link = $10::text)), $11)
+					ON CONFLICT (canonical_link) DO NOTHING RETURNING id
+				), media AS (
+					INSERT INTO news_media(news_id, url, type, width, height)
+					SELECT inserted.id, m.url, m.type, m.width, m.height
+					FROM inserted, unnest($12::text[], $13::text[], $14::int[], $15::int[]) WITH ORDINALITY AS m(url, type, width, height, n)
+					ORDER BY m.n
+				)
+				SELECT id FROM inserted`,
+				n.Title,
+				n.Content,
+				n.PubTime,
+				n.Link,
+				sourceId,
+				n.Body,
+				links[i],
+				int64(n.Fingerprint),
+				clusterId,
+				clusterLink,
+				hash,
+				media.urls,
+				media.types,
+				media.widths,
+				media.heights,
+			)
+			queued = append(queued, i)
+			results[i].Result = storage.NewsInserted
+			pending[links[i]] = true
+			if n.Fingerprint != 0 {
+				candidates = append(candidates, clusterCandidate{link: links[i], fingerprint: n.Fingerprint, pubTime: n.PubTime})
+			}
+		}
+		if len(queued) == 0 {
+			return nil
+		}
+		br := tx.SendBatch(ctx, batch)
+		for _, i := range queued {
+			if results[i].Result != storage.NewsInserted {
+				if _, err := br.Exec(); err != nil {
+					br.Close()
+					return err
+				}
+				continue
+			}
+			var id int
+			err := br.QueryRow().Scan(&id)
+			// Строка не вставлена - новость одновременно добавлена другим запросом
+			if errors.Is(err, pgx.ErrNoRows) {
+				results[i].Result = storage.NewsUnchanged
+				continue
+			}
+			if err != nil {
+				br.Close()
+				return err
+			}
+		}
+		return br.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Выбирает сохраненные новости по каноническим ссылкам и блокирует их до конца транзакции
+func findStoredNews(ctx context.Context, tx pgx.Tx, links []string) (map[string]storedNews, error) {
+	rows, err := tx.Query(
+		ctx,
+		`SELECT canonical_link, id, source_id, content_hash FROM news WHERE canonical_link = ANY($1) FOR UPDATE`,
+		links,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stored := make(map[string]storedNews)
+	for rows.Next() {
+		var (
+			link string
+			n    storedNews
+		)
+		if err := rows.Scan(&link, &n.id, &n.sourceId, &n.hash); err != nil {
+			return nil, err
+		}
+		stored[link] = n
+	}
+	return stored, rows.Err()
+}
+
+// Расстояние Хэмминга между отпечатками news.fingerprint и t.fingerprint, bit_count есть только с PostgreSQL 14
+const fingerprintDistance = `length(replace((news.fingerprint # t.fingerprint)::bit(64)::text, '0', ''))`
+
+// Выбирает новости, похожие хотя бы на одну из добавляемых: опубликованные в пределах storage.ClusterWindow
+// от нее и с отпечатком не дальше storage.ClusterDistance, в порядке добавления. Окно выбирается по индексу
+// news_fingerprint_pub_time_idx, расстояние считается в БД, чтобы не загружать все новости окна.
+func findClusterCandidates(ctx context.Context, tx pgx.Tx, pubTimes []int64, fingerprints []int64) ([]clusterCandidate, error) {
+	if len(pubTimes) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(
+		ctx,
+		`SELECT `+newsCluster+`, fingerprint, pub_time FROM news
+		WHERE fingerprint <> 0 AND EXISTS (
+			SELECT 1 FROM unnest($1::bigint[], $2::bigint[]) AS t(pub_time, fingerprint)
+			WHERE news.pub_time BETWEEN t.pub_time - $3 AND t.pub_time + $3 AND `+fingerprintDistance+` <= $4
+		)
+		ORDER BY id`,
+		pubTimes,
+		fingerprints,
+		storage.ClusterWindow,
+		storage.ClusterDistance,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var candidates []clusterCandidate
+	for rows.Next() {
+		var (
+			c           clusterCandidate
+			cluster     int
+			fingerprint int64
+		)
+		if err := rows.Scan(&cluster, &fingerprint, &c.pubTime); err != nil {
+			return nil, err
+		}
+		c.cluster, c.fingerprint = &cluster, uint64(fingerprint)
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// Ищет группу похожих новостей для добавляемой новости: кандидата с ближайшим отпечатком среди новостей,
+// опубликованных в пределах storage.ClusterWindow. Возвращает nil, если похожих новостей нет.
+func nearestCluster(candidates []clusterCandidate, news storage.NewsShortDetailed) *clusterCandidate {
+	if news.Fingerprint == 0 {
+		return nil
+	}
+	var (
+		nearest *clusterCandidate
+		best    = storage.ClusterDistance + 1
+	)
+	for i, c := range candidates {
+		if c.pubTime < news.PubTime-storage.ClusterWindow || c.pubTime > news.PubTime+storage.ClusterWindow {
+			continue
+		}
+		if d := storage.Distance(c.fingerprint, news.Fingerprint); d < best {
+			best, nearest = d, &candidates[i]
+		}
+	}
+	return nearest
+}
+
+// Вложения новости по столбцам для вставки одним запросом через unnest
+type mediaArrays struct {
+	urls, types     []string
+	widths, heights []int
+}
+
+// Раскладывает вложения новости по столбцам
+func mediaColumns(media []storage.Media) mediaArrays {
+	var a mediaArrays
+	for _, m := range media {
+		a.urls = append(a.urls, m.URL)
+		a.types = append(a.types, m.Type)
+		a.widths = append(a.widths, m.Width)
+		a.heights = append(a.heights, m.Height)
+	}
+	return a
+}
+
+// Проверяет, что идентификаторы источников совпадают, в том числе оба пустые
+func sameSource(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// Дополняет новости их изображениями и вложениями, порядок вложений - порядок добавления
+func (s *Store) attachMedia(ctx context.Context, news []storage.NewsShortDetailed) error {
+	if len(news) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(news))
+	ids := make([]int, 0, len(news))
+	for i, n := range news {
+		index[n.Id] = i
+		ids = append(ids, n.Id)
+	}
+	rows, err := s.Pool.Query(
+		ctx,
+		`SELECT news_id, url, type, width, height FROM news_media WHERE news_id = ANY($1) ORDER BY id`,
+		ids,
 	)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			newsId int
+			m      storage.Media
+		)
+		if err := rows.Scan(&newsId, &m.URL, &m.Type, &m.Width, &m.Height); err != nil {
+			return err
+		}
+		i := index[newsId]
+		news[i].Media = append(news[i].Media, m)
+	}
+	return rows.Err()
 }
 
-// Метод получения списка новостей
-func (s *Store) News(offset, limit int, search string) ([]storage.NewsShortDetailed, int, error) {
+// Присоединение сведений об источнике к выборке новостей. Столбцы источника переименованы, чтобы не пересекаться
+// со столбцами новостей в условиях отбора и сортировки
+const sourceJoin = `LEFT JOIN (SELECT id AS src_id, title AS src_title, site_url AS src_site_url FROM sources) src ON src_id = source_id`
+
+// Выборка новостей со сведениями об источнике
+const newsFrom = `news ` + sourceJoin
+
+// Группа новости, пустой cluster_id - группа из первой новости
+const newsCluster = `COALESCE(cluster_id, id)`
+
+// Столбцы новости со сведениями об источнике в порядке scanNews
+const newsColumns = `id, title, content, pub_time, link, ` + newsCluster + `, updated_at, source_id, COALESCE(src_title, ''), COALESCE(src_site_url, '')`
+
+// Сканирует строку новости, выбранную столбцами newsColumns, и следующие за ними дополнительные столбцы
+func scanNews(row pgx.Row, extra ...any) (storage.NewsShortDetailed, error) {
+	var (
+		n        storage.NewsShortDetailed
+		sourceId *int
+		source   storage.NewsSource
+	)
+	dest := append([]any{&n.Id, &n.Title, &n.Content, &n.PubTime, &n.Link, &n.ClusterId, &n.UpdatedAt, &sourceId, &source.Title, &source.SiteURL}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return storage.NewsShortDetailed{}, err
+	}
+	if sourceId != nil {
+		source.Id = *sourceId
+		n.Source = &source
+	}
+	return n, nil
+}
+
+// Выражение полнотекстового запроса по правилам русского и английского языков
+const tsQuery = `(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))`
+
+// Выражение хоста ссылки на новость, приведенного к виду storage.Host
+const linkHost = `regexp_replace(lower(substring(link from '^[a-zA-Z][a-zA-Z0-9+.-]*://([^/:?#]+)')), '^www\.', '')`
+
+// Построитель условий запроса к списку новостей, значения передаются только параметрами
+type newsFilter struct {
+	where []string
+	args  []any
+	query string // Выражение полнотекстового запроса с подставленным параметром
+}
+
+// Добавляет значение в параметры запроса и возвращает его плейсхолдер
+func (f *newsFilter) arg(v any) string {
+	f.args = append(f.args, v)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+// Собирает условия отбора новостей по параметрам выборки
+func filterNews(q storage.NewsQuery) *newsFilter {
+	f := &newsFilter{}
+	switch {
+	case q.FullText && q.Search != "":
+		f.query = fmt.Sprintf(tsQuery, f.arg(q.Search))
+		f.where = append(f.where, "search_vector @@ "+f.query)
+	case q.Search != "":
+		// Спецсимволы LIKE в строке поиска экранируются
+		f.where = append(f.where, "LOWER(title) LIKE "+f.arg("%"+escapeLike(strings.ToLower(q.Search))+"%")+` ESCAPE '\'`)
+	}
+	if q.From != 0 {
+		f.where = append(f.where, "pub_time >= "+f.arg(q.From))
+	}
+	if q.To != 0 {
+		f.where = append(f.where, "pub_time <= "+f.arg(q.To))
+	}
+	if q.Source != "" {
+		f.where = append(f.where, linkHost+" = "+f.arg(q.Source))
+	}
+	if q.SourceId != 0 {
+		f.where = append(f.where, "source_id = "+f.arg(q.SourceId))
+	}
+	if q.SourceURL != "" {
+		f.where = append(f.where, "source_id IN (SELECT s.id FROM sources s WHERE s.url = "+f.arg(q.SourceURL)+")")
+	}
+	return f
+}
+
+// Возвращает условие WHERE для запроса
+func (f *newsFilter) condition() string {
+	return and(f.where)
+}
+
+// Объединяет условия через AND, пустой список - условие TRUE
+func and(conditions []string) string {
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Возвращает выражение FROM выборки новостей и условия отбора для него. При группировке похожих новостей
+// новости отбираются во вложенном запросе, из каждой группы остается первая в порядке order новость.
+func (f *newsFilter) from(q storage.NewsQuery, order string) (string, []string) {
+	if !q.Collapse {
+		return newsFrom, slices.Clone(f.where)
+	}
+	from := fmt.Sprintf(
+		`(SELECT *, row_number() OVER (PARTITION BY %s ORDER BY %s) AS cluster_rank FROM news WHERE %s) news %s`,
+		newsCluster, order, f.condition(), sourceJoin,
+	)
+	return from, []string{"cluster_rank = 1"}
+}
+
+// Возвращает выражение фрагмента текста с подсветкой найденных слов
+func (f *newsFilter) snippet(q storage.NewsQuery) string {
+	if !q.Highlight || f.query == "" {
+		return "''"
+	}
+	return fmt.Sprintf(`ts_headline('russian', content, %s, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=10')`, f.query)
+}
+
+// Возвращает выражение ORDER BY, при равенстве значений порядок задает идентификатор
+func (f *newsFilter) order(q storage.NewsQuery) string {
+	direction := "DESC"
+	if q.Asc {
+		direction = "ASC"
+	}
+	switch {
+	case q.Sort == storage.SortPubTime:
+		return fmt.Sprintf("pub_time %[1]s, id %[1]s", direction)
+	case q.Sort == storage.SortId:
+		return "id " + direction
+	case f.query != "":
+		return fmt.Sprintf("ts_rank(search_vector, %[1]s) %[2]s, id %[2]s", f.query, direction)
+	default:
+		return "id " + direction
+	}
+}
+
+// Метод получения списка новостей по параметрам выборки и общего количества подходящих новостей.
+// Если задан полнотекстовый поиск, заголовок весит больше текста, слова приводятся к основе по правилам
+// русского и английского языков, по-умолчанию новости упорядочены по релевантности.
+func (s *Store) News(ctx context.Context, q storage.NewsQuery) ([]storage.NewsShortDetailed, int, error) {
 	var (
 		news  []storage.NewsShortDetailed
 		count int
 	)
-	// Получаем общее число строк в ответе
+	f := filterNews(q)
+	// Получаем общее число строк в ответе, при группировке - число групп
+	counted := "*"
+	if q.Collapse {
+		counted = "DISTINCT " + newsCluster
+	}
 	err := s.Pool.QueryRow(
-		context.Background(),
-		`SELECT count(*) FROM news WHERE LOWER(title) LIKE '%`+search+`%'`,
+		ctx,
+		`SELECT count(`+counted+`) FROM news WHERE `+f.condition(),
+		f.args...,
 	).Scan(&count)
 	if err != nil {
 		return news, 0, err
 	}
 
 	// Получаем только строки с нужной страницы
-	rows, err := s.Pool.Query(
-		context.Background(),
-		`SELECT id, title, content, pub_time, link FROM news WHERE LOWER(title) LIKE '%`+search+`%' ORDER BY id DESC OFFSET $1 LIMIT $2`,
-		offset,
-		limit,
+	order := f.order(q)
+	from, where := f.from(q, order)
+	sql := fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s ORDER BY %s OFFSET %s LIMIT %s`,
+		newsColumns, f.snippet(q), from, and(where), order, f.arg(q.Offset), f.arg(q.Limit),
 	)
+	rows, err := s.Pool.Query(ctx, sql, f.args...)
 	if err != nil {
 		return news, 0, err
 	}
+	defer rows.Close()
 	// Итерируем по строкам, записываем результат
 	for rows.Next() {
-		n := storage.NewsShortDetailed{}
-		err := rows.Scan(
-			&n.Id,
-			&n.Title,
-			&n.Content,
-			&n.PubTime,
-			&n.Link,
-		)
+		var snippet string
+		n, err := scanNews(rows, &snippet)
 		if err != nil {
 			return news, 0, err
 		}
+		n.Snippet = snippet
 		news = append(news, n)
 	}
 	if rows.Err() != nil {
 		return news, 0, rows.Err()
 	}
+	if err := s.attachMedia(ctx, news); err != nil {
+		return news, 0, err
+	}
+	if q.Collapse {
+		if err := s.attachAlternates(ctx, news); err != nil {
+			return news, 0, err
+		}
+	}
 	return news, count, nil
 }
 
-// Метод получения детальной новости по идентификатору
-func (s *Store) NewsByID(id int) (storage.NewsShortDetailed, error) {
-	var news storage.NewsShortDetailed
+// Метод получения страницы новостей относительно курсора, без подсчета общего количества.
+// Новости отдаются по убыванию (pub_time, id), при nil курсоре - с начала списка. Из параметров выборки
+// учитываются условия отбора и Limit, сортировка и смещение задаются курсором.
+// Второе возвращаемое значение сообщает, есть ли еще новости дальше в направлении курсора.
+func (s *Store) NewsPage(ctx context.Context, cursor *storage.Cursor, q storage.NewsQuery) ([]storage.NewsShortDetailed, bool, error) {
+	var news []storage.NewsShortDetailed
+	f := filterNews(q)
+	order := "pub_time DESC, id DESC"
+	// Группа представлена самой новой новостью независимо от направления вывода
+	from, where := f.from(q, order)
+	if cursor != nil {
+		// Страницу перед курсором выбираем в обратном порядке и затем разворачиваем
+		if cursor.Before {
+			where = append(where, fmt.Sprintf("(pub_time, id) > (%s, %s)", f.arg(cursor.PubTime), f.arg(cursor.Id)))
+			order = "pub_time ASC, id ASC"
+		} else {
+			where = append(where, fmt.Sprintf("(pub_time, id) < (%s, %s)", f.arg(cursor.PubTime), f.arg(cursor.Id)))
+		}
+	}
+	// Запрашиваем на одну новость больше, чтобы узнать, есть ли следующая страница
+	sql := fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s ORDER BY %s LIMIT %s`,
+		newsColumns, f.snippet(q), from, and(where), order, f.arg(q.Limit+1),
+	)
+	rows, err := s.Pool.Query(ctx, sql, f.args...)
+	if err != nil {
+		return news, false, err
+	}
+	defer rows.Close()
+	// Итерируем по строкам, записываем результат
+	for rows.Next() {
+		var snippet string
+		n, err := scanNews(rows, &snippet)
+		if err != nil {
+			return news, false, err
+		}
+		n.Snippet = snippet
+		news = append(news, n)
+	}
+	if rows.Err() != nil {
+		return news, false, rows.Err()
+	}
+	more := len(news) > q.Limit
+	if more {
+		news = news[:q.Limit]
+	}
+	if err := s.attachMedia(ctx, news); err != nil {
+		return news, false, err
+	}
+	if q.Collapse {
+		if err := s.attachAlternates(ctx, news); err != nil {
+			return news, false, err
+		}
+	}
+	if cursor != nil && cursor.Before {
+		for i, j := 0, len(news)-1; i < j; i, j = i+1, j-1 {
+			news[i], news[j] = news[j], news[i]
+		}
+	}
+	return news, more, nil
+}
 
-	err := s.Pool.QueryRow(
-		context.Background(),
-		`SELECT id, title, content, pub_time, link FROM news WHERE id = $1`,
-		id,
-	).Scan(
-		&news.Id,
-		&news.Title,
-		&news.Content,
-		&news.PubTime,
-		&news.Link,
+// Дополняет новости, представляющие группы похожих новостей, размером группы и остальными новостями группы
+// по убыванию времени публикации
+func (s *Store) attachAlternates(ctx context.Context, news []storage.NewsShortDetailed) error {
+	if len(news) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(news))
+	clusters := make([]int, 0, len(news))
+	for i := range news {
+		index[news[i].ClusterId] = i
+		clusters = append(clusters, news[i].ClusterId)
+		news[i].ClusterSize = 1
+	}
+	rows, err := s.Pool.Query(
+		ctx,
+		`SELECT `+newsCluster+`, id, title, link, source_id, COALESCE(src_title, ''), COALESCE(src_site_url, '')
+		FROM `+newsFrom+` WHERE cluster_id = ANY($1) OR id = ANY($1) ORDER BY pub_time DESC, id DESC`,
+		clusters,
 	)
 	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cluster  int
+			a        storage.Alternate
+			sourceId *int
+			source   storage.NewsSource
+		)
+		if err := rows.Scan(&cluster, &a.Id, &a.Title, &a.Link, &sourceId, &source.Title, &source.SiteURL); err != nil {
+			return err
+		}
+		i, exist := index[cluster]
+		if !exist || a.Id == news[i].Id {
+			continue
+		}
+		if sourceId != nil {
+			source.Id = *sourceId
+			a.Source = &source
+		}
+		news[i].Alternates = append(news[i].Alternates, a)
+		news[i].ClusterSize++
+	}
+	return rows.Err()
+}
+
+// Экранирует спецсимволы шаблона LIKE, чтобы строка поиска сравнивалась буквально
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Метод получения детальной новости по идентификатору, вместе с полным текстом
+func (s *Store) NewsByID(ctx context.Context, id int) (storage.NewsShortDetailed, error) {
+	var body string
+	news, err := scanNews(s.Pool.QueryRow(ctx, `SELECT `+newsColumns+`, body FROM `+newsFrom+` WHERE id = $1`, id), &body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.NewsShortDetailed{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.NewsShortDetailed{}, err
+	}
+	news.Body = body
+	result := []storage.NewsShortDetailed{news}
+	if err := s.attachMedia(ctx, result); err != nil {
 		return storage.NewsShortDetailed{}, err
 	}
-	return news, nil
+	return result[0], nil
+}
+
+// Метод получения предыдущих версий новости, от последней к первой
+func (s *Store) NewsRevisions(ctx context.Context, id int) ([]storage.Revision, error) {
+	var revisions []storage.Revision
+	rows, err := s.Pool.Query(
+		ctx,
+		`SELECT id, title, content, body, replaced_at FROM news_revisions WHERE news_id = $1 ORDER BY id DESC`,
+		id,
+	)
+	if err != nil {
+		return revisions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r storage.Revision
+		if err := rows.Scan(&r.Id, &r.Title, &r.Content, &r.Body, &r.ReplacedAt); err != nil {
+			return revisions, err
+		}
+		revisions = append(revisions, r)
+	}
+	if rows.Err() != nil {
+		return revisions, rows.Err()
+	}
+	return revisions, nil
 }
 
 // Метод добавления коментария
-func (s *Store) AddComment(comment storage.Comment) error {
+func (s *Store) AddComment(ctx context.Context, comment storage.Comment) error {
 	_, err := s.Pool.Exec(
-		context.Background(),
+		ctx,
 		`INSERT INTO comments (news_id, comment_id, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
 		comment.NewsId,
 		comment.CommentId,
@@ -123,17 +856,18 @@ func (s *Store) AddComment(comment storage.Comment) error {
 }
 
 // Метод получение списка комментариев к новости
-func (s *Store) CommentsByNewsId(id int) ([]storage.Comment, error) {
+func (s *Store) CommentsByNewsId(ctx context.Context, id int) ([]storage.Comment, error) {
 	var comments []storage.Comment
 
 	rows, err := s.Pool.Query(
-		context.Background(),
-		`SELECT id, news_id, comment_id, content, created_at, updated_at FROM comments WHERE news_id = $1`,
+		ctx,
+		`SELECT id, news_id, comment_id, content, created_at, updated_at FROM comments WHERE news_id = $1 ORDER BY id`,
 		id,
 	)
 	if err != nil {
 		return comments, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var comment storage.Comment
 		err := rows.Scan(
@@ -156,15 +890,16 @@ func (s *Store) CommentsByNewsId(id int) ([]storage.Comment, error) {
 }
 
 // Метод получения словаря запрещенных слов
-func (s *Store) Dictionary() ([]string, error) {
+func (s *Store) Dictionary(ctx context.Context) ([]string, error) {
 	var words []string
 	rows, err := s.Pool.Query(
-		context.Background(),
+		ctx,
 		`SELECT word FROM dictionary`,
 	)
 	if err != nil {
 		return []string{}, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var word string
 		err = rows.Scan(
@@ -179,9 +914,9 @@ func (s *Store) Dictionary() ([]string, error) {
 }
 
 // Метод добавления запрещенного слова в словарь
-func (s *Store) AddWord2Dictionary(word string) error {
+func (s *Store) AddWord2Dictionary(ctx context.Context, word string) error {
 	_, err := s.Pool.Exec(
-		context.Background(),
+		ctx,
 		`INSERT INTO dictionary (word) VALUES ($1)`,
 		word,
 	)
@@ -190,3 +925,182 @@ func (s *Store) AddWord2Dictionary(word string) error {
 	}
 	return nil
 }
+
+// Столбцы таблицы sources в порядке полей storage.Source
+const sourceColumns = `id, title, enabled, poll_interval, category, url, etag, last_modified,
+	failures, last_error, last_error_at, healthy, last_status, last_fetch_at, site_url`
+
+// Сканирует строку таблицы sources, выбранную столбцами sourceColumns
+func scanSource(row pgx.Row) (storage.Source, error) {
+	var src storage.Source
+	err := row.Scan(
+		&src.Id,
+		&src.Title,
+		&src.Enabled,
+		&src.PollInterval,
+		&src.Category,
+		&src.URL,
+		&src.ETag,
+		&src.LastModified,
+		&src.Failures,
+		&src.LastError,
+		&src.LastErrorAt,
+		&src.Healthy,
+		&src.LastStatus,
+		&src.LastFetchAt,
+		&src.SiteURL,
+	)
+	return src, err
+}
+
+// Проверяет, является ли ошибка нарушением уникальности
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Метод получения списка источников новостей
+func (s *Store) Sources(ctx context.Context) ([]storage.Source, error) {
+	var sources []storage.Source
+	rows, err := s.Pool.Query(ctx, `SELECT `+sourceColumns+` FROM sources ORDER BY id`)
+	if err != nil {
+		return sources, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		src, err := scanSource(rows)
+		if err != nil {
+			return sources, err
+		}
+		sources = append(sources, src)
+	}
+	if rows.Err() != nil {
+		return sources, rows.Err()
+	}
+	return sources, nil
+}
+
+// Метод получения источника новостей по идентификатору
+func (s *Store) SourceByID(ctx context.Context, id int) (storage.Source, error) {
+	src, err := scanSource(s.Pool.QueryRow(ctx, `SELECT `+sourceColumns+` FROM sources WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Source{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.Source{}, err
+	}
+	return src, nil
+}
+
+// Метод добавления источника новостей, возвращает идентификатор нового источника
+func (s *Store) AddSource(ctx context.Context, src storage.Source) (int, error) {
+	var id int
+	err := s.Pool.QueryRow(
+		ctx,
+		`INSERT INTO sources (url, title, enabled, poll_interval, category) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		src.URL,
+		src.Title,
+		src.Enabled,
+		src.PollInterval,
+		src.Category,
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, storage.ErrDuplicateSource
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Метод изменения адреса, названия, признака включения, периода опроса и категории источника.
+// При смене адреса состояние опроса сбрасывается.
+func (s *Store) UpdateSource(ctx context.Context, src storage.Source) error {
+	tag, err := s.Pool.Exec(
+		ctx,
+		`UPDATE sources SET
+			title = $2,
+			enabled = $3,
+			poll_interval = $4,
+			category = $6,
+			etag = CASE WHEN url = $5 THEN etag ELSE '' END,
+			last_modified = CASE WHEN url = $5 THEN last_modified ELSE '' END,
+			failures = CASE WHEN url = $5 THEN failures ELSE 0 END,
+			last_error = CASE WHEN url = $5 THEN last_error ELSE '' END,
+			last_error_at = CASE WHEN url = $5 THEN last_error_at ELSE 0 END,
+			healthy = CASE WHEN url = $5 THEN healthy ELSE TRUE END,
+			last_status = CASE WHEN url = $5 THEN last_status ELSE '' END,
+			last_fetch_at = CASE WHEN url = $5 THEN last_fetch_at ELSE 0 END,
+			site_url = CASE WHEN url = $5 THEN site_url ELSE '' END,
+			url = $5
+		WHERE id = $1`,
+		src.Id,
+		src.Title,
+		src.Enabled,
+		src.PollInterval,
+		src.URL,
+		src.Category,
+	)
+	if isUniqueViolation(err) {
+		return storage.ErrDuplicateSource
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Метод удаления источника новостей
+func (s *Store) DeleteSource(ctx context.Context, id int) error {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM sources WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Метод сохранения состояния опроса источника с заданным адресом
+func (s *Store) SaveFeedState(ctx context.Context, state storage.FeedState) error {
+	tag, err := s.Pool.Exec(
+		ctx,
+		`UPDATE sources SET
+			etag = $2,
+			last_modified = $3,
+			failures = $4,
+			last_error = $5,
+			last_error_at = $6,
+			healthy = $7,
+			last_status = $8,
+			last_fetch_at = $9,
+			site_url = $10
+		WHERE url = $1`,
+		state.URL,
+		state.ETag,
+		state.LastModified,
+		state.Failures,
+		state.LastError,
+		state.LastErrorAt,
+		state.Healthy,
+		state.LastStatus,
+		state.LastFetchAt,
+		state.SiteURL,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// Метод закрытия пула соединений с БД
+func (s *Store) Close() {
+	s.Pool.Close()
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+	"github.com/antibaloo/sf-final-project/internal/storage/storagetest"
+)
+
+// Проверки выполняются только на тестовой БД из переменной окружения storagetest.PostgresEnv
+func TestStore(t *testing.T) {
+	if _, exist := os.LookupEnv(storagetest.PostgresEnv); !exist {
+		t.Skipf("не задана переменная окружения %s", storagetest.PostgresEnv)
+	}
+	storagetest.Run(t, storagetest.Postgres)
+}
+
+func TestCanonicalizeLinks(t *testing.T) {
+	db := storagetest.Postgres(t).(*postgres.Store)
+	ctx := context.Background()
+	// Новости, сохраненные до миграции 20261018170000_news_canonical_link.sql: каноническая ссылка - исходная
+	_, err := db.Pool.Exec(
+		ctx,
+		`INSERT INTO news (title, content, link, canonical_link) VALUES
+			('Первая', 'content', 'http://example.com/a?utm_source=rss', 'http://example.com/a?utm_source=rss'),
+			('Повтор первой', 'content', 'https://EXAMPLE.com/a/', 'https://EXAMPLE.com/a/'),
+			('Каноническая', 'content', 'https://example.com/b', 'https://example.com/b'),
+			('Занятая', 'content', 'http://example.com/b', 'http://example.com/b')`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := db.CanonicalizeLinks(ctx)
+	if err != nil || updated != 1 {
+		t.Fatalf("CanonicalizeLinks = %d, %v, ожидалась 1 новость", updated, err)
+	}
+	// Перенос выполняется один раз
+	if updated, err := db.CanonicalizeLinks(ctx); err != nil || updated != 0 {
+		t.Errorf("повторный CanonicalizeLinks = %d, %v", updated, err)
+	}
+	// Та же новость из фида с канонической ссылкой не добавляется повторно
+	n := storage.NewsShortDetailed{Title: "Первая", Content: "content", Link: "https://example.com/a"}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsUnchanged {
+		t.Errorf("AddNews(%q) = %v, %v, ожидалось %v", n.Link, result, err, storage.NewsUnchanged)
+	}
+	rows, err := db.Pool.Query(ctx, `SELECT title, canonical_link FROM news ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	want := [][2]string{
+		{"Первая", "https://example.com/a"},
+		// Каноническую ссылку получает первая новость, у остальных остается исходная
+		{"Повтор первой", "https://EXAMPLE.com/a/"},
+		{"Каноническая", "https://example.com/b"},
+		{"Занятая", "http://example.com/b"},
+	}
+	var got [][2]string
+	for rows.Next() {
+		var title, link string
+		if err := rows.Scan(&title, &link); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, [2]string{title, link})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("новости %q, ожидалось %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("новость %d = %q, ожидалось %q", i, got[i], want[i])
+		}
+	}
+}
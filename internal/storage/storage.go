@@ -1,5 +1,20 @@
 package storage
 
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrNotFound        = errors.New("запись не найдена")                       // Запрошенная запись отсутствует в хранилище
+	ErrDuplicateSource = errors.New("источник с таким адресом уже существует") // Нарушение уникальности адреса источника
+)
+
 // Структура комментария
 type Comment struct {
 	Id        int    `json:"id"`         // Идентификатор комментария, первичный ключ
@@ -12,11 +27,75 @@ type Comment struct {
 
 // Структура сокращенной новости
 type NewsShortDetailed struct {
-	Id      int    `json:"id"`       //Идентификатор
-	Title   string `json:"title"`    //Заголовок новости
-	Content string `json:"content"`  // Первый абзац новости
-	PubTime int64  `json:"pub_time"` // Время публикации новости в источнике
-	Link    string `json:"link"`     // Ссылка на источник
+	Id          int         `json:"id"`                     //Идентификатор
+	Title       string      `json:"title"`                  //Заголовок новости
+	Content     string      `json:"content"`                // Первый абзац новости
+	PubTime     int64       `json:"pub_time"`               // Время публикации новости в источнике
+	Link        string      `json:"link"`                   // Ссылка на источник
+	Aliases     []string    `json:"-"`                      // Другие ссылки на новость для поиска повторов, не сохраняются
+	Snippet     string      `json:"snippet,omitempty"`      // Фрагмент текста с подсвеченными словами поиска
+	Body        string      `json:"body,omitempty"`         // Полный текст новости очищенным html, только в детальной новости
+	Source      *NewsSource `json:"source,omitempty"`       // Источник новости, при добавлении достаточно идентификатора
+	Media       []Media     `json:"media,omitempty"`        // Изображения и вложения новости
+	Fingerprint uint64      `json:"-"`                      // Отпечаток SimHash для поиска похожих новостей, 0 - нет, не выбирается
+	ClusterId   int         `json:"cluster_id"`             // Группа похожих новостей, идентификатор первой новости группы
+	ClusterSize int         `json:"cluster_size,omitempty"` // Количество новостей в группе, только при группировке
+	Alternates  []Alternate `json:"alternates,omitempty"`   // Остальные новости группы, только при группировке
+	UpdatedAt   int64       `json:"updated_at,omitempty"`   // Время последнего изменения заголовка или текста, 0 - не менялись
+	Revisions   []Revision  `json:"revisions,omitempty"`    // Предыдущие версии, только в детальной новости по запросу
+}
+
+// Предыдущая версия новости, сохраняется при изменении заголовка или текста в источнике
+type Revision struct {
+	Id         int    `json:"id"`             // Идентификатор версии
+	Title      string `json:"title"`          // Заголовок новости
+	Content    string `json:"content"`        // Краткое содержание новости
+	Body       string `json:"body,omitempty"` // Полный текст новости очищенным html
+	ReplacedAt int64  `json:"replaced_at"`    // Время, когда версию сменила следующая
+}
+
+// Результаты добавления новости
+const (
+	NewsInserted  = "inserted"  // Новость добавлена
+	NewsUpdated   = "updated"   // Новость уже была, ее заголовок или текст изменились и обновлены
+	NewsUnchanged = "unchanged" // Новость уже была и не изменилась или пришла из другого источника
+	NewsFailed    = "failed"    // Новость не удалось записать, только в результатах AddNewsBatch
+)
+
+// Результат добавления новости из пакета
+type BatchResult struct {
+	Result string // NewsInserted, NewsUpdated, NewsUnchanged или NewsFailed
+	Err    error  // Причина ошибки для NewsFailed
+}
+
+// Возвращает хэш заголовка и текста новости, по которому определяются изменения новости в источнике.
+// Совпадает с выражением, которым хэш заполнен для новостей, сохраненных до миграции 20261018190000_news_revisions.sql.
+func ContentHash(news NewsShortDetailed) string {
+	sum := sha256.Sum256([]byte(news.Title + "\x1f" + news.Content + "\x1f" + news.Body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Другая новость из группы похожих новостей
+type Alternate struct {
+	Id     int         `json:"id"`               // Идентификатор новости
+	Title  string      `json:"title"`            // Заголовок новости
+	Link   string      `json:"link"`             // Ссылка на новость
+	Source *NewsSource `json:"source,omitempty"` // Источник новости
+}
+
+// Изображение или вложение новости, сам файл не загружается, хранится только ссылка на него
+type Media struct {
+	URL    string `json:"url"`              // Адрес файла
+	Type   string `json:"type"`             // MIME тип файла, если неизвестен - вид: image, video, audio
+	Width  int    `json:"width,omitempty"`  // Ширина в пикселях, 0 - неизвестна
+	Height int    `json:"height,omitempty"` // Высота в пикселях, 0 - неизвестна
+}
+
+// Краткие сведения об источнике новости
+type NewsSource struct {
+	Id      int    `json:"id"`       // Идентификатор источника
+	Title   string `json:"title"`    // Название источника
+	SiteURL string `json:"site_url"` // Адрес сайта источника
 }
 
 // Структура детальной новости
@@ -25,13 +104,164 @@ type NewsFullDetailed struct {
 	Comments []Comment `json:"comments"` // Комментарии к новости
 }
 
-// Контракт на методы  хранилища
+// Состояние опроса фида, сохраняется между запусками сервиса
+type FeedState struct {
+	URL          string `json:"url"`           // Адрес фида
+	ETag         string `json:"etag"`          // Значение заголовка ETag последнего полного ответа
+	LastModified string `json:"last_modified"` // Значение заголовка Last-Modified последнего полного ответа
+	Failures     int    `json:"failures"`      // Количество ошибок чтения подряд
+	LastError    string `json:"last_error"`    // Текст последней ошибки чтения
+	LastErrorAt  int64  `json:"last_error_at"` // Время последней ошибки чтения
+	Healthy      bool   `json:"healthy"`       // Фид читается без ошибок или ошибок подряд меньше порога
+	LastStatus   string `json:"last_status"`   // Результат последнего опроса, см. FetchOK и др.
+	LastFetchAt  int64  `json:"last_fetch_at"` // Время последнего опроса
+	SiteURL      string `json:"site_url"`      // Адрес сайта, указанный в фиде
+}
+
+// Результаты опроса фида
+const (
+	FetchOK          = "ok"           // Фид прочитан
+	FetchNotModified = "not_modified" // Фид не изменился с прошлого опроса
+	FetchError       = "error"        // При чтении фида произошла ошибка
+)
+
+// Структура источника новостей. Адрес, название, признак включения, период опроса и категория задаются через API,
+// состояние опроса ведет ридер новостей
+type Source struct {
+	Id           int    `json:"id"`            // Идентификатор источника
+	Title        string `json:"title"`         // Название источника
+	Enabled      bool   `json:"enabled"`       // Опрашивать ли источник
+	PollInterval int    `json:"poll_interval"` // Период опроса в минутах, 0 - период ридера по-умолчанию
+	Category     string `json:"category"`      // Категория источника, вложенные категории разделяются "/"
+	FeedState
+}
+
+// Параметры группировки похожих новостей: новость попадает в группу ближайшей по отпечатку новости,
+// если отпечатки отличаются не больше чем в ClusterDistance битах, а время публикации - не больше чем на ClusterWindow.
+// Порог подобран по парам перепечаток и несвязанных новостей из internal/rss/simhash_test.go: перепечатки
+// одной новости отличаются в 6-16 битах, несвязанные новости, в том числе на одну тему, - в 21 и больше.
+const (
+	ClusterDistance = 18            // Наибольшее расстояние Хэмминга между отпечатками похожих новостей
+	ClusterWindow   = 3 * 24 * 3600 // Наибольшая разница времени публикации похожих новостей, в секундах
+)
+
+// Возвращает расстояние Хэмминга между отпечатками новостей
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Поля сортировки списка новостей
+const (
+	SortId        = "id"        // По идентификатору, т.е. по времени добавления в БД
+	SortPubTime   = "pub_time"  // По времени публикации в источнике
+	SortRelevance = "relevance" // По релевантности, только для полнотекстового поиска
+)
+
+// Параметры выборки списка новостей
+type NewsQuery struct {
+	Search    string // Строка поиска
+	FullText  bool   // Полнотекстовый поиск по заголовку и тексту вместо поиска по вхождению в заголовок
+	Highlight bool   // Возвращать фрагмент текста с подсвеченными словами поиска (только для полнотекстового поиска)
+	From      int64  // Время публикации не раньше, 0 - без ограничения
+	To        int64  // Время публикации не позже, 0 - без ограничения
+	Source    string // Хост ссылки на новость (см. Host), пустая строка - любой
+	SourceURL string // Адрес фида источника новости, пустая строка - любой
+	SourceId  int    // Идентификатор источника новости, 0 - любой
+	Collapse  bool   // Группировать похожие новости: из группы отдается первая по порядку сортировки новость
+	Sort      string // Поле сортировки, по-умолчанию SortRelevance для полнотекстового поиска и SortId для остальных
+	Asc       bool   // Сортировка по возрастанию, по-умолчанию по убыванию
+	Offset    int    // Смещение от начала списка
+	Limit     int    // Количество новостей на странице
+}
+
+// Возвращает хост ссылки в нижнем регистре и без префикса www., ссылка может быть задана без схемы
+func Host(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Параметры запроса, которые добавляют рекламные и аналитические системы. Они не меняют страницу, на которую ведет ссылка.
+var trackingParams = map[string]bool{
+	"fbclid":    true,
+	"gclid":     true,
+	"dclid":     true,
+	"yclid":     true,
+	"msclkid":   true,
+	"igshid":    true,
+	"mc_cid":    true,
+	"mc_eid":    true,
+	"_openstat": true,
+}
+
+// Возвращает каноническую ссылку на новость, по которой определяются повторы одной новости из разных фидов:
+// схема http заменяется на https, хост приводится к нижнему регистру, порт по-умолчанию, фрагмент, параметры
+// отслеживания (utm_* и др., см. trackingParams) и завершающий слэш пути удаляются, остальные параметры
+// упорядочиваются по имени. Ссылки, которые не удалось разобрать, и ссылки не http(s) возвращаются без изменений.
+func CanonicalLink(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return link
+	}
+	u.Scheme = "https"
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment, u.RawFragment = "", ""
+	query := u.Query()
+	for name := range query {
+		if strings.HasPrefix(strings.ToLower(name), "utm_") || trackingParams[strings.ToLower(name)] {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u.String()
+}
+
+// Позиция курсора при постраничном выводе новостей в порядке убывания (pub_time, id)
+type Cursor struct {
+	PubTime int64 // Время публикации новости, на которой стоит курсор
+	Id      int   // Идентификатор новости, на которой стоит курсор
+	Before  bool  // Нужна страница перед курсором, иначе - после него
+}
+
+// Контракт на методы  хранилища, все методы прерываются при отмене переданного контекста.
+// AddNews не добавляет новость, если новость с той же канонической ссылкой (см. CanonicalLink) уже есть. Если
+// у новости из того же источника изменился хэш заголовка и текста (см. ContentHash), новость обновляется,
+// а предыдущая версия сохраняется в истории. Возвращает результат: NewsInserted, NewsUpdated или NewsUnchanged.
+// AddNewsBatch добавляет новости так же, как AddNews, и возвращает результат для каждой новости в порядке пакета.
+// Ошибка одной новости не мешает записи остальных, ошибка метода означает, что результаты пакета неизвестны.
 type Store interface {
-	AddNews(NewsShortDetailed) error
-	News(int, int, string) ([]NewsShortDetailed, int, error)
-	NewsByID(int) (NewsShortDetailed, error)
-	AddComment(Comment) error
-	CommentsByNewsId(int) ([]Comment, error)
-	Dictionary() ([]string, error)
-	AddWord2Dictionary(string) error
+	AddNews(context.Context, NewsShortDetailed) (string, error)
+	AddNewsBatch(context.Context, []NewsShortDetailed) ([]BatchResult, error)
+	News(context.Context, NewsQuery) ([]NewsShortDetailed, int, error)
+	NewsPage(context.Context, *Cursor, NewsQuery) ([]NewsShortDetailed, bool, error)
+	NewsByID(context.Context, int) (NewsShortDetailed, error)
+	NewsRevisions(context.Context, int) ([]Revision, error)
+	AddComment(context.Context, Comment) error
+	CommentsByNewsId(context.Context, int) ([]Comment, error)
+	Dictionary(context.Context) ([]string, error)
+	AddWord2Dictionary(context.Context, string) error
+	Sources(context.Context) ([]Source, error)
+	SourceByID(context.Context, int) (Source, error)
+	AddSource(context.Context, Source) (int, error)
+	UpdateSource(context.Context, Source) error
+	DeleteSource(context.Context, int) error
+	SaveFeedState(context.Context, FeedState) error
+	Close()
 }
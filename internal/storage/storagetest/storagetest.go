@@ -0,0 +1,1182 @@
+// Пакет storagetest содержит общий набор проверок для любой реализации storage.Store.
+// Новое хранилище считается взаимозаменяемым с существующими, если проходит Run.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/antibaloo/sf-final-project/internal/storage"
+	"github.com/antibaloo/sf-final-project/internal/storage/postgres"
+)
+
+// Переменная окружения со строкой подключения к тестовой БД PostgreSQL с примененными миграциями.
+// Если она не задана, проверки хранилища PostgreSQL пропускаются.
+const PostgresEnv = "STORAGE_TEST_POSTGRES"
+
+// Фабрика хранилища, каждый вызов должен возвращать новое хранилище в состоянии сразу после миграций
+type Factory func(t *testing.T) storage.Store
+
+// Фабрика хранилища PostgreSQL: подключается к тестовой БД из PostgresEnv и очищает таблицы перед проверкой
+func Postgres(t *testing.T) storage.Store {
+	t.Helper()
+	conString, exist := os.LookupEnv(PostgresEnv)
+	if !exist {
+		t.Skipf("не задана переменная окружения %s", PostgresEnv)
+	}
+	db, err := postgres.NewStore(conString)
+	if err != nil {
+		t.Fatalf("ошибка при соединении с БД: %v", err)
+	}
+	// Словарь после очистки заполняется так же, как миграцией 20241023100328_create.sql
+	_, err = db.Pool.Exec(
+		context.Background(),
+		`TRUNCATE news, comments, dictionary, sources, backfills RESTART IDENTITY CASCADE;
+		INSERT INTO dictionary (word) VALUES ('xxx'),('yyy'),('zzz')`,
+	)
+	if err != nil {
+		db.Close()
+		t.Fatalf("ошибка при очистке БД: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// Запускает полный набор проверок для хранилища, создаваемого фабрикой
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Store)
+	}{
+		{"AddNewsAndNewsByID", testAddNewsAndNewsByID},
+		{"NewsByIDNotFound", testNewsByIDNotFound},
+		{"DuplicateLink", testDuplicateLink},
+		{"LinkAliases", testLinkAliases},
+		{"NewsPagination", testNewsPagination},
+		{"NewsFilters", testNewsFilters},
+		{"NewsSort", testNewsSort},
+		{"NewsPage", testNewsPage},
+		{"NewsSearch", testNewsSearch},
+		{"NewsSearchHostile", testNewsSearchHostile},
+		{"NewsFullText", testNewsFullText},
+		{"CommentsOrder", testCommentsOrder},
+		{"CommentReply", testCommentReply},
+		{"CommentUnknownNews", testCommentUnknownNews},
+		{"Dictionary", testDictionary},
+		{"Sources", testSources},
+		{"FeedState", testFeedState},
+		{"NewsSource", testNewsSource},
+		{"NewsSourceURL", testNewsSourceURL},
+		{"NewsMedia", testNewsMedia},
+		{"NewsCollapse", testNewsCollapse},
+		{"NewsRevisions", testNewsRevisions},
+		{"LegacyNewsRevisions", testLegacyNewsRevisions},
+		{"AddNewsBatch", testAddNewsBatch},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// Добавляет n новостей с заголовками title-0..title-(n-1) и возвращает их в порядке добавления
+func addNews(t *testing.T, db storage.Store, title string, n int) []storage.NewsShortDetailed {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		_, err := db.AddNews(ctx, storage.NewsShortDetailed{
+			Title:   fmt.Sprintf("%s-%d", title, i),
+			Content: "content",
+			PubTime: int64(1700000000 + i),
+			Link:    fmt.Sprintf("https://example.com/%s/%d", title, i),
+		})
+		if err != nil {
+			t.Fatalf("AddNews(%d): %v", i, err)
+		}
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Limit: n, Search: title})
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	if count != n || len(news) != n {
+		t.Fatalf("News: получено %d из %d новостей", len(news), count)
+	}
+	// Список отдается по убыванию идентификатора, разворачиваем
+	for i, j := 0, len(news)-1; i < j; i, j = i+1, j-1 {
+		news[i], news[j] = news[j], news[i]
+	}
+	return news
+}
+
+func testAddNewsAndNewsByID(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	want := storage.NewsShortDetailed{
+		Title:   "Заголовок",
+		Content: "Текст новости",
+		PubTime: 1700000000,
+		Link:    "https://example.com/1",
+		Body:    "<p>Текст новости</p><p>Второй абзац</p>",
+	}
+	if _, err := db.AddNews(ctx, want); err != nil {
+		t.Fatalf("AddNews: %v", err)
+	}
+	news, _, err := db.News(ctx, storage.NewsQuery{Limit: 1})
+	if err != nil || len(news) != 1 {
+		t.Fatalf("News: %v, %v", news, err)
+	}
+	// Полный текст отдается только в детальной новости
+	if news[0].Body != "" {
+		t.Errorf("News вернул полный текст %q", news[0].Body)
+	}
+	got, err := db.NewsByID(ctx, news[0].Id)
+	if err != nil {
+		t.Fatalf("NewsByID: %v", err)
+	}
+	// Новость без похожих новостей - единственная в своей группе
+	want.Id, want.ClusterId = news[0].Id, news[0].Id
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewsByID = %+v, ожидалось %+v", got, want)
+	}
+}
+
+func testNewsByIDNotFound(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	if _, err := db.NewsByID(ctx, 100500); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("NewsByID для несуществующей новости вернул %v, ожидалось %v", err, storage.ErrNotFound)
+	}
+}
+
+func testDuplicateLink(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	n := storage.NewsShortDetailed{Title: "Первая", Content: "content", Link: "https://example.com/dup?id=1&page=2"}
+	result, err := db.AddNews(ctx, n)
+	if err != nil || result != storage.NewsInserted {
+		t.Fatalf("AddNews = %v, %v", result, err)
+	}
+	// Ссылки, которые отличаются только схемой, регистром хоста, параметрами отслеживания, порядком параметров,
+	// фрагментом или завершающим слэшем, ведут на ту же новость
+	for _, link := range []string{
+		"https://example.com/dup?id=1&page=2",
+		"http://example.com/dup?id=1&page=2",
+		"https://EXAMPLE.com:443/dup/?page=2&id=1",
+		"https://example.com/dup?utm_source=rss&utm_medium=feed&id=1&page=2&fbclid=abc#comments",
+	} {
+		n.Link = link
+		result, err := db.AddNews(ctx, n)
+		if err != nil || result != storage.NewsUnchanged {
+			t.Errorf("AddNews(%q) = %v, %v, ожидалось %v", link, result, err, storage.NewsUnchanged)
+		}
+	}
+	// Другой параметр - другая новость
+	n.Title, n.Link = "Третья", "https://example.com/dup?id=2&page=2"
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Errorf("AddNews(%q) = %v, %v", n.Link, result, err)
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	if count != 2 || len(news) != 2 || news[1].Title != "Первая" || news[1].Link != "https://example.com/dup?id=1&page=2" {
+		t.Errorf("после дубликатов: %d новостей, %+v", count, news)
+	}
+}
+
+func testLinkAliases(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	n := storage.NewsShortDetailed{Title: "Первая", Content: "content", Link: "https://example.com/click?to=1"}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Fatalf("AddNews = %v, %v", result, err)
+	}
+	// Та же новость с другой ссылкой находится по прежней ссылке и обновляется, а не добавляется повторно
+	n.Title, n.Link, n.Aliases = "Первая, исправлено", "https://example.com/news/1", []string{"http://example.com/click?to=1&utm_source=rss"}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsUpdated {
+		t.Errorf("AddNews по другой ссылке = %v, %v, ожидалось %v", result, err, storage.NewsUpdated)
+	}
+	results, err := db.AddNewsBatch(ctx, []storage.NewsShortDetailed{n})
+	if err != nil || len(results) != 1 || results[0].Result != storage.NewsUnchanged {
+		t.Errorf("AddNewsBatch по другой ссылке = %+v, %v, ожидалось %v", results, err, storage.NewsUnchanged)
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	if count != 1 || len(news) != 1 || news[0].Title != "Первая, исправлено" || news[0].Link != "https://example.com/click?to=1" {
+		t.Errorf("после другой ссылки: %d новостей, %+v", count, news)
+	}
+	// Новость без сохраненных прежних ссылок добавляется по своей ссылке
+	n.Title, n.Link, n.Aliases = "Вторая", "https://example.com/news/2", []string{"https://example.com/click?to=2"}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Errorf("AddNews(%q) = %v, %v", n.Link, result, err)
+	}
+}
+
+func testNewsPagination(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	all := addNews(t, db, "page", 7)
+	tests := []struct {
+		offset, limit int
+		ids           []int
+	}{
+		{0, 3, []int{all[6].Id, all[5].Id, all[4].Id}},
+		{3, 3, []int{all[3].Id, all[2].Id, all[1].Id}},
+		{6, 3, []int{all[0].Id}},
+		{9, 3, nil},
+	}
+	for _, tt := range tests {
+		news, count, err := db.News(ctx, storage.NewsQuery{Offset: tt.offset, Limit: tt.limit})
+		if err != nil {
+			t.Fatalf("News(%d, %d): %v", tt.offset, tt.limit, err)
+		}
+		if count != len(all) {
+			t.Errorf("News(%d, %d): count = %d, ожидалось %d", tt.offset, tt.limit, count, len(all))
+		}
+		var ids []int
+		for _, n := range news {
+			ids = append(ids, n.Id)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Errorf("News(%d, %d) = %v, ожидалось %v", tt.offset, tt.limit, ids, tt.ids)
+		}
+	}
+}
+
+// Добавляет новости из разных источников с разным временем публикации для проверок отбора и сортировки
+func addSourcedNews(t *testing.T, db storage.Store) {
+	t.Helper()
+	ctx := context.Background()
+	items := []storage.NewsShortDetailed{
+		{Title: "a", PubTime: 300, Link: "https://habr.com/ru/articles/1/"},
+		{Title: "b", PubTime: 100, Link: "https://www.habr.com/ru/articles/2/"},
+		{Title: "c", PubTime: 500, Link: "http://golangweekly.com/issues/3"},
+		{Title: "d", PubTime: 200, Link: "https://blog.habr.com/4"},
+		{Title: "e", PubTime: 400, Link: "https://HABR.com:443/ru/articles/5?utm=1"},
+	}
+	for _, n := range items {
+		n.Content = "content"
+		if _, err := db.AddNews(ctx, n); err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+}
+
+// Возвращает заголовки новостей одной строкой
+func titles(news []storage.NewsShortDetailed) string {
+	var s string
+	for _, n := range news {
+		s += n.Title
+	}
+	return s
+}
+
+func testNewsFilters(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	addSourcedNews(t, db)
+	tests := []struct {
+		name  string
+		query storage.NewsQuery
+		want  string
+	}{
+		{"без отбора", storage.NewsQuery{}, "edcba"},
+		{"from", storage.NewsQuery{From: 300}, "eca"},
+		{"to", storage.NewsQuery{To: 200}, "db"},
+		{"from и to", storage.NewsQuery{From: 200, To: 400}, "eda"},
+		{"source", storage.NewsQuery{Source: "habr.com"}, "eba"},
+		{"source из адреса фида", storage.NewsQuery{Source: storage.Host("https://habr.com/ru/rss/hub/go/all/?fl=ru")}, "eba"},
+		{"source поддомен", storage.NewsQuery{Source: "blog.habr.com"}, "d"},
+		{"source и to", storage.NewsQuery{Source: "habr.com", To: 300}, "ba"},
+		{"неизвестный source", storage.NewsQuery{Source: "example.com"}, ""},
+	}
+	for _, tt := range tests {
+		tt.query.Limit = 10
+		news, count, err := db.News(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("News(%s): %v", tt.name, err)
+		}
+		if got := titles(news); got != tt.want || count != len(tt.want) {
+			t.Errorf("News(%s) = %q (%d), ожидалось %q", tt.name, got, count, tt.want)
+		}
+	}
+	// Отбор учитывается и при выводе по курсору
+	news, more, err := db.NewsPage(ctx, nil, storage.NewsQuery{Source: "habr.com", From: 200, Limit: 10})
+	if err != nil || more || titles(news) != "ea" {
+		t.Errorf("NewsPage с отбором = %q (%v), %v", titles(news), more, err)
+	}
+}
+
+func testNewsSort(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	addSourcedNews(t, db)
+	tests := []struct {
+		query storage.NewsQuery
+		want  string
+	}{
+		{storage.NewsQuery{Sort: storage.SortId}, "edcba"},
+		{storage.NewsQuery{Sort: storage.SortId, Asc: true}, "abcde"},
+		{storage.NewsQuery{Sort: storage.SortPubTime}, "ceadb"},
+		{storage.NewsQuery{Sort: storage.SortPubTime, Asc: true}, "bdaec"},
+		{storage.NewsQuery{Sort: storage.SortPubTime, Offset: 1, Limit: 2}, "ea"},
+	}
+	for _, tt := range tests {
+		if tt.query.Limit == 0 {
+			tt.query.Limit = 10
+		}
+		news, _, err := db.News(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("News(%+v): %v", tt.query, err)
+		}
+		if got := titles(news); got != tt.want {
+			t.Errorf("News(%+v) = %q, ожидалось %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// Проход по курсору вперед и назад должен отдавать все новости по убыванию (pub_time, id) без пропусков и повторов,
+// даже если между запросами страниц добавлены новые новости
+func testNewsPage(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	// У части новостей одинаковое время публикации, порядок между ними задает идентификатор
+	pubTimes := []int64{100, 300, 200, 200, 500, 200, 400}
+	for i, pubTime := range pubTimes {
+		_, err := db.AddNews(ctx, storage.NewsShortDetailed{
+			Title:   fmt.Sprintf("cursor-%d", i),
+			Content: "content",
+			PubTime: pubTime,
+			Link:    fmt.Sprintf("https://example.com/cursor/%d", i),
+		})
+		if err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	key := func(n storage.NewsShortDetailed) string { return fmt.Sprintf("%d/%s", n.PubTime, n.Title) }
+	want := []string{"500/cursor-4", "400/cursor-6", "300/cursor-1", "200/cursor-5", "200/cursor-3", "200/cursor-2", "100/cursor-0"}
+
+	// Идем вперед по 3 новости
+	var (
+		got    []string
+		cursor *storage.Cursor
+		pages  [][]storage.NewsShortDetailed
+	)
+	for {
+		news, more, err := db.NewsPage(ctx, cursor, storage.NewsQuery{Limit: 3, Search: "cursor"})
+		if err != nil {
+			t.Fatalf("NewsPage: %v", err)
+		}
+		pages = append(pages, news)
+		for _, n := range news {
+			got = append(got, key(n))
+		}
+		if len(pages) == 1 {
+			// Новая новость после загрузки первой страницы не должна сдвигать следующие
+			_, err := db.AddNews(ctx, storage.NewsShortDetailed{Title: "cursor-new", Content: "content", PubTime: 600, Link: "https://example.com/cursor/new"})
+			if err != nil {
+				t.Fatalf("AddNews: %v", err)
+			}
+		}
+		if !more {
+			break
+		}
+		last := news[len(news)-1]
+		cursor = &storage.Cursor{PubTime: last.PubTime, Id: last.Id}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("NewsPage вперед = %v, ожидалось %v", got, want)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("NewsPage вперед: %d страниц, ожидалось 3", len(pages))
+	}
+
+	// Идем назад от последней страницы
+	first := pages[2][0]
+	news, more, err := db.NewsPage(ctx, &storage.Cursor{PubTime: first.PubTime, Id: first.Id, Before: true}, storage.NewsQuery{Limit: 3, Search: "cursor"})
+	if err != nil {
+		t.Fatalf("NewsPage: %v", err)
+	}
+	if fmt.Sprint(news) != fmt.Sprint(pages[1]) || !more {
+		t.Errorf("NewsPage назад = %v (%v), ожидалось %v", news, more, pages[1])
+	}
+	first = news[0]
+	news, more, err = db.NewsPage(ctx, &storage.Cursor{PubTime: first.PubTime, Id: first.Id, Before: true}, storage.NewsQuery{Limit: 3, Search: "cursor"})
+	if err != nil {
+		t.Fatalf("NewsPage: %v", err)
+	}
+	// Перед первой страницей теперь есть добавленная новость
+	if len(news) != 3 || key(news[0]) != "500/cursor-4" || !more {
+		t.Errorf("NewsPage назад = %v (%v)", news, more)
+	}
+}
+
+func testNewsSearch(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	for i, title := range []string{"Выпуск Go 1.23", "Новости golang", "Про Rust", "GOPHERCON"} {
+		_, err := db.AddNews(ctx, storage.NewsShortDetailed{
+			Title:   title,
+			Content: "content",
+			Link:    fmt.Sprintf("https://example.com/search/%d", i),
+		})
+		if err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	tests := []struct {
+		search string
+		count  int
+	}{
+		{"", 4},
+		{"go", 3},
+		{"GO", 3},
+		{"Rust", 1},
+		{"выпуск", 1},
+		{"python", 0},
+	}
+	for _, tt := range tests {
+		news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10, Search: tt.search})
+		if err != nil {
+			t.Fatalf("News(%q): %v", tt.search, err)
+		}
+		if count != tt.count || len(news) != tt.count {
+			t.Errorf("News(%q): count = %d, len = %d, ожидалось %d", tt.search, count, len(news), tt.count)
+		}
+	}
+}
+
+// Строка поиска должна сравниваться буквально: без SQL-инъекций и без спецсимволов LIKE
+func testNewsSearchHostile(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	titles := []string{"Скидка 100% на Go", "snake_case в Go", `C:\go\bin`, "Статья O'Reilly", "Обычная новость"}
+	for i, title := range titles {
+		_, err := db.AddNews(ctx, storage.NewsShortDetailed{
+			Title:   title,
+			Content: "content",
+			Link:    fmt.Sprintf("https://example.com/hostile/%d", i),
+		})
+		if err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	tests := []struct {
+		search string
+		count  int
+	}{
+		{"%", 1},
+		{"100%", 1},
+		{"_", 1},
+		{"e_c", 1},
+		{`\`, 1},
+		{`\%`, 0},
+		{"'", 1},
+		{"o'reilly", 1},
+		{"' OR '1'='1", 0},
+		{"'; DROP TABLE news; --", 0},
+		{"%' OR title LIKE '%", 0},
+		{`\'; SELECT pg_sleep(1); --`, 0},
+	}
+	for _, tt := range tests {
+		news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10, Search: tt.search})
+		if err != nil {
+			t.Fatalf("News(%q): %v", tt.search, err)
+		}
+		if count != tt.count || len(news) != tt.count {
+			t.Errorf("News(%q): count = %d, len = %d, ожидалось %d", tt.search, count, len(news), tt.count)
+		}
+	}
+	// Таблица новостей не должна пострадать
+	_, count, err := db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil || count != len(titles) {
+		t.Errorf("после поиска: count = %d, %v", count, err)
+	}
+}
+
+func testNewsFullText(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	items := []storage.NewsShortDetailed{
+		{Title: "Weekly digest", Content: "The new compiler is faster and smaller"},
+		{Title: "Compiler internals", Content: "How the compiler works"},
+		{Title: "Gardening tips", Content: "Nothing about programming here"},
+		{Title: "Release notes", Content: "What is new this week", Body: `<p>The <a href="https://example.com/compiler">compiler</a> got faster</p>`},
+	}
+	for i, n := range items {
+		n.Link = fmt.Sprintf("https://example.com/fts/%d", i)
+		if _, err := db.AddNews(ctx, n); err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Search: "compiler", FullText: true, Highlight: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("News (полнотекстовый): %v", err)
+	}
+	if count != 3 || len(news) != 3 {
+		t.Fatalf("News (полнотекстовый): count = %d, len = %d, ожидалось 3", count, len(news))
+	}
+	// Совпадение в заголовке релевантнее совпадения в кратком содержании, а оно - совпадения только в полном тексте
+	if got := titles(news); got != "Compiler internals"+"Weekly digest"+"Release notes" {
+		t.Errorf("News (полнотекстовый): порядок %q", got)
+	}
+	for _, n := range news[:2] {
+		if !strings.Contains(n.Snippet, "<mark>") {
+			t.Errorf("News (полнотекстовый): нет подсветки во фрагменте %q", n.Snippet)
+		}
+	}
+	news, _, err = db.News(ctx, storage.NewsQuery{Search: "compiler", FullText: true, Limit: 10})
+	if err != nil || len(news) != 3 || news[0].Snippet != "" {
+		t.Errorf("News (полнотекстовый) без подсветки: %+v, %v", news, err)
+	}
+	news, count, err = db.News(ctx, storage.NewsQuery{Search: "python", FullText: true, Limit: 10})
+	if err != nil || count != 0 || len(news) != 0 {
+		t.Errorf("News (полнотекстовый) без совпадений: %d, %+v, %v", count, news, err)
+	}
+}
+
+func testCommentsOrder(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	news := addNews(t, db, "comments", 2)
+	for i := 0; i < 3; i++ {
+		for _, n := range news {
+			err := db.AddComment(ctx, storage.Comment{NewsId: n.Id, Content: fmt.Sprintf("%d-%d", n.Id, i)})
+			if err != nil {
+				t.Fatalf("AddComment: %v", err)
+			}
+		}
+	}
+	comments, err := db.CommentsByNewsId(ctx, news[1].Id)
+	if err != nil {
+		t.Fatalf("CommentsByNewsId: %v", err)
+	}
+	if len(comments) != 3 {
+		t.Fatalf("CommentsByNewsId: %d комментариев, ожидалось 3", len(comments))
+	}
+	for i, c := range comments {
+		if c.NewsId != news[1].Id || c.Content != fmt.Sprintf("%d-%d", news[1].Id, i) {
+			t.Errorf("комментарий %d: %+v", i, c)
+		}
+		if c.CreatedAt == 0 || c.UpdatedAt == 0 {
+			t.Errorf("комментарий %d без времени создания: %+v", i, c)
+		}
+		if i > 0 && c.Id <= comments[i-1].Id {
+			t.Errorf("комментарии не упорядочены по идентификатору: %+v", comments)
+		}
+	}
+	comments, err = db.CommentsByNewsId(ctx, 100500)
+	if err != nil || len(comments) != 0 {
+		t.Errorf("CommentsByNewsId для новости без комментариев: %v, %v", comments, err)
+	}
+}
+
+func testCommentReply(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	news := addNews(t, db, "reply", 1)
+	if err := db.AddComment(ctx, storage.Comment{NewsId: news[0].Id, Content: "вопрос"}); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	comments, err := db.CommentsByNewsId(ctx, news[0].Id)
+	if err != nil || len(comments) != 1 {
+		t.Fatalf("CommentsByNewsId: %v, %v", comments, err)
+	}
+	parent := comments[0]
+	if parent.CommentId != 0 {
+		t.Errorf("корневой комментарий ссылается на %d", parent.CommentId)
+	}
+	if err := db.AddComment(ctx, storage.Comment{NewsId: news[0].Id, CommentId: parent.Id, Content: "ответ"}); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	comments, err = db.CommentsByNewsId(ctx, news[0].Id)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("CommentsByNewsId: %v, %v", comments, err)
+	}
+	if comments[1].CommentId != parent.Id || comments[1].Content != "ответ" {
+		t.Errorf("ответ не связан с комментарием %d: %+v", parent.Id, comments[1])
+	}
+}
+
+func testCommentUnknownNews(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	if err := db.AddComment(ctx, storage.Comment{NewsId: 100500, Content: "в пустоту"}); err == nil {
+		t.Error("AddComment к несуществующей новости не вернул ошибку")
+	}
+}
+
+func testDictionary(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	words, err := db.Dictionary(ctx)
+	slices.Sort(words)
+	if err != nil || !slices.Equal(words, []string{"xxx", "yyy", "zzz"}) {
+		t.Fatalf("Dictionary нового хранилища: %v, %v", words, err)
+	}
+	for _, word := range []string{"aaa", "bbb"} {
+		if err := db.AddWord2Dictionary(ctx, word); err != nil {
+			t.Fatalf("AddWord2Dictionary(%q): %v", word, err)
+		}
+	}
+	if err := db.AddWord2Dictionary(ctx, "xxx"); err == nil {
+		t.Error("AddWord2Dictionary с повторным словом не вернул ошибку")
+	}
+	words, err = db.Dictionary(ctx)
+	if err != nil {
+		t.Fatalf("Dictionary: %v", err)
+	}
+	if len(words) != 5 {
+		t.Errorf("Dictionary = %v, ожидалось 5 слов", words)
+	}
+}
+
+func testSources(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	sources, err := db.Sources(ctx)
+	if err != nil || len(sources) != 0 {
+		t.Fatalf("Sources пустого хранилища = %+v, %v", sources, err)
+	}
+	first, err := db.AddSource(ctx, storage.Source{Title: "Пример", Enabled: true, Category: "Новости/Мир", FeedState: storage.FeedState{URL: "https://example.com/feed.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	second, err := db.AddSource(ctx, storage.Source{PollInterval: 15, FeedState: storage.FeedState{URL: "https://example.com/atom.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	if _, err := db.AddSource(ctx, storage.Source{FeedState: storage.FeedState{URL: "https://example.com/feed.xml"}}); !errors.Is(err, storage.ErrDuplicateSource) {
+		t.Errorf("AddSource дубликата вернул %v, ожидалось %v", err, storage.ErrDuplicateSource)
+	}
+	want := storage.Source{Id: first, Title: "Пример", Enabled: true, Category: "Новости/Мир", FeedState: storage.FeedState{URL: "https://example.com/feed.xml", Healthy: true}}
+	src, err := db.SourceByID(ctx, first)
+	if err != nil || src != want {
+		t.Errorf("SourceByID = %+v, %v, ожидалось %+v", src, err, want)
+	}
+	sources, err = db.Sources(ctx)
+	if err != nil || len(sources) != 2 || sources[0].Id != first || sources[1].Id != second || sources[1].PollInterval != 15 {
+		t.Errorf("Sources = %+v, %v", sources, err)
+	}
+
+	// Изменение адреса на занятый другим источником
+	src.URL = "https://example.com/atom.xml"
+	if err := db.UpdateSource(ctx, src); !errors.Is(err, storage.ErrDuplicateSource) {
+		t.Errorf("UpdateSource с занятым адресом вернул %v, ожидалось %v", err, storage.ErrDuplicateSource)
+	}
+	if err := db.UpdateSource(ctx, storage.Source{Id: 1000, FeedState: storage.FeedState{URL: "https://example.com/none"}}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateSource неизвестного источника вернул %v, ожидалось %v", err, storage.ErrNotFound)
+	}
+	// Отключение источника состояние опроса не сбрасывает
+	state := storage.FeedState{URL: "https://example.com/feed.xml", ETag: `"abc"`, Healthy: true, LastStatus: storage.FetchOK, LastFetchAt: 1700000000, SiteURL: "https://example.com/"}
+	if err := db.SaveFeedState(ctx, state); err != nil {
+		t.Fatalf("SaveFeedState: %v", err)
+	}
+	want.Title, want.Enabled, want.PollInterval, want.Category, want.FeedState = "Отключен", false, 30, "Архив", state
+	if err := db.UpdateSource(ctx, storage.Source{Id: first, Title: "Отключен", PollInterval: 30, Category: "Архив", FeedState: storage.FeedState{URL: state.URL}}); err != nil {
+		t.Fatalf("UpdateSource: %v", err)
+	}
+	if src, err := db.SourceByID(ctx, first); err != nil || src != want {
+		t.Errorf("SourceByID после изменения = %+v, %v, ожидалось %+v", src, err, want)
+	}
+	// Смена адреса сбрасывает состояние опроса
+	want.FeedState = storage.FeedState{URL: "https://example.com/rss.xml", Healthy: true}
+	if err := db.UpdateSource(ctx, storage.Source{Id: first, Title: "Отключен", PollInterval: 30, Category: "Архив", FeedState: storage.FeedState{URL: want.URL}}); err != nil {
+		t.Fatalf("UpdateSource: %v", err)
+	}
+	if src, err := db.SourceByID(ctx, first); err != nil || src != want {
+		t.Errorf("SourceByID после смены адреса = %+v, %v, ожидалось %+v", src, err, want)
+	}
+
+	if err := db.DeleteSource(ctx, second); err != nil {
+		t.Fatalf("DeleteSource: %v", err)
+	}
+	if err := db.DeleteSource(ctx, second); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteSource повторно вернул %v, ожидалось %v", err, storage.ErrNotFound)
+	}
+	if _, err := db.SourceByID(ctx, second); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("SourceByID удаленного источника вернул %v, ожидалось %v", err, storage.ErrNotFound)
+	}
+}
+
+func testFeedState(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	const url = "https://example.com/feed.xml"
+	// Состояние сохраняется только для известного источника
+	if err := db.SaveFeedState(ctx, storage.FeedState{URL: url}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("SaveFeedState неизвестного источника вернул %v, ожидалось %v", err, storage.ErrNotFound)
+	}
+	id, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: url}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	want := storage.FeedState{URL: url, ETag: `"abc"`, LastModified: "Mon, 06 May 2024 10:00:00 GMT", Healthy: true}
+	if err := db.SaveFeedState(ctx, want); err != nil {
+		t.Fatalf("SaveFeedState: %v", err)
+	}
+	want.ETag = `W/"def"`
+	want.Failures, want.LastError, want.LastErrorAt, want.Healthy = 3, "timeout", 1700000000, false
+	want.LastStatus, want.LastFetchAt, want.SiteURL = storage.FetchError, 1700000000, "https://example.com/"
+	if err := db.SaveFeedState(ctx, want); err != nil {
+		t.Fatalf("SaveFeedState повторно: %v", err)
+	}
+	src, err := db.SourceByID(ctx, id)
+	if err != nil || src.FeedState != want || !src.Enabled {
+		t.Errorf("SourceByID = %+v, %v, ожидалось состояние %+v", src, err, want)
+	}
+}
+
+func testNewsSource(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	const url = "https://example.com/feed.xml"
+	id, err := db.AddSource(ctx, storage.Source{Title: "Пример", Enabled: true, FeedState: storage.FeedState{URL: url}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	if err := db.SaveFeedState(ctx, storage.FeedState{URL: url, Healthy: true, SiteURL: "https://example.com/"}); err != nil {
+		t.Fatalf("SaveFeedState: %v", err)
+	}
+	_, err = db.AddNews(ctx, storage.NewsShortDetailed{Title: "С источником", Content: "content", PubTime: 1700000000, Link: "https://example.com/1", Source: &storage.NewsSource{Id: id}})
+	if err != nil {
+		t.Fatalf("AddNews: %v", err)
+	}
+	_, err = db.AddNews(ctx, storage.NewsShortDetailed{Title: "Без источника", Content: "content", PubTime: 1700000001, Link: "https://example.com/2"})
+	if err != nil {
+		t.Fatalf("AddNews: %v", err)
+	}
+	_, err = db.AddNews(ctx, storage.NewsShortDetailed{Title: "Чужой источник", Content: "content", Link: "https://example.com/3", Source: &storage.NewsSource{Id: id + 1000}})
+	if err == nil {
+		t.Error("AddNews с неизвестным источником не вернул ошибку")
+	}
+
+	want := storage.NewsSource{Id: id, Title: "Пример", SiteURL: "https://example.com/"}
+	news, count, err := db.News(ctx, storage.NewsQuery{SourceId: id, Limit: 10})
+	if err != nil || count != 1 || len(news) != 1 || news[0].Source == nil || *news[0].Source != want {
+		t.Fatalf("News по источнику = %+v, %d, %v", news, count, err)
+	}
+	page, _, err := db.NewsPage(ctx, nil, storage.NewsQuery{Limit: 10})
+	if err != nil || len(page) != 2 || page[0].Source != nil || page[1].Source == nil || *page[1].Source != want {
+		t.Errorf("NewsPage = %+v, %v", page, err)
+	}
+	n, err := db.NewsByID(ctx, news[0].Id)
+	if err != nil || n.Source == nil || *n.Source != want {
+		t.Errorf("NewsByID = %+v, %v", n, err)
+	}
+
+	// Удаление источника удаляет его новости
+	if err := db.DeleteSource(ctx, id); err != nil {
+		t.Fatalf("DeleteSource: %v", err)
+	}
+	news, _, err = db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil || titles(news) != "Без источника" {
+		t.Errorf("после удаления источника остались новости %q, %v", titles(news), err)
+	}
+}
+
+func testNewsSourceURL(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	// Агрегатор: фид на одном хосте, ссылки новостей - на других
+	const feed = "https://feeds.example.org/all.xml"
+	id, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: feed}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	other, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://blog.example.com/feed.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	for i, n := range []storage.NewsShortDetailed{
+		{Title: "a", Link: "https://blog.example.com/a", Source: &storage.NewsSource{Id: id}},
+		{Title: "b", Link: "https://click.example.net/?to=b", Source: &storage.NewsSource{Id: id}},
+		{Title: "c", Link: "https://blog.example.com/c", Source: &storage.NewsSource{Id: other}},
+		{Title: "d", Link: "https://feeds.example.org/d"},
+	} {
+		n.Content, n.PubTime = "content", int64(1700000000+i)
+		if _, err := db.AddNews(ctx, n); err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	tests := []struct {
+		name  string
+		q     storage.NewsQuery
+		want  string
+		count int
+	}{
+		// По адресу фида - новости источника, куда бы ни вели их ссылки
+		{"адрес фида", storage.NewsQuery{SourceURL: feed}, "ba", 2},
+		{"адрес фида и хост", storage.NewsQuery{SourceURL: feed, Source: "blog.example.com"}, "a", 1},
+		{"неизвестный фид", storage.NewsQuery{SourceURL: "https://feeds.example.org/other.xml"}, "", 0},
+		// По хосту - новости, ссылки на которые ведут на этот хост
+		{"хост", storage.NewsQuery{Source: "feeds.example.org"}, "d", 1},
+	}
+	for _, tt := range tests {
+		tt.q.Limit = 10
+		news, count, err := db.News(ctx, tt.q)
+		if err != nil || titles(news) != tt.want || count != tt.count {
+			t.Errorf("News(%s) = %q, %d, %v, ожидалось %q, %d", tt.name, titles(news), count, err, tt.want, tt.count)
+		}
+		page, _, err := db.NewsPage(ctx, nil, tt.q)
+		if err != nil || titles(page) != tt.want {
+			t.Errorf("NewsPage(%s) = %q, %v, ожидалось %q", tt.name, titles(page), err, tt.want)
+		}
+	}
+}
+
+func testNewsMedia(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	media := []storage.Media{
+		{URL: "https://example.com/1.jpg", Type: "image/jpeg", Width: 640, Height: 480},
+		{URL: "https://example.com/1.mp3", Type: "audio/mpeg"},
+		{URL: "https://example.com/inline.png", Type: "image"},
+	}
+	if _, err := db.AddNews(ctx, storage.NewsShortDetailed{Title: "С вложениями", Content: "content", Link: "https://example.com/media/1", Media: media}); err != nil {
+		t.Fatalf("AddNews: %v", err)
+	}
+	if _, err := db.AddNews(ctx, storage.NewsShortDetailed{Title: "Без вложений", Content: "content", Link: "https://example.com/media/2"}); err != nil {
+		t.Fatalf("AddNews: %v", err)
+	}
+	// Вложения отдаются и в списке, и в детальной новости в порядке добавления
+	news, _, err := db.News(ctx, storage.NewsQuery{Limit: 10, Sort: storage.SortId, Asc: true})
+	if err != nil || len(news) != 2 {
+		t.Fatalf("News: %v, %v", news, err)
+	}
+	if !reflect.DeepEqual(news[0].Media, media) {
+		t.Errorf("News: вложения %+v, ожидалось %+v", news[0].Media, media)
+	}
+	if len(news[1].Media) != 0 {
+		t.Errorf("News: у новости без вложений вложения %+v", news[1].Media)
+	}
+	page, _, err := db.NewsPage(ctx, nil, storage.NewsQuery{Limit: 10})
+	if err != nil || len(page) != 2 || !reflect.DeepEqual(page[1].Media, media) {
+		t.Errorf("NewsPage: %+v, %v", page, err)
+	}
+	got, err := db.NewsByID(ctx, news[0].Id)
+	if err != nil || !reflect.DeepEqual(got.Media, media) {
+		t.Errorf("NewsByID: вложения %+v, %v", got.Media, err)
+	}
+	// Изменение полученных вложений не меняет сохраненную новость
+	got.Media[0].URL = "https://example.com/changed.jpg"
+	if again, _ := db.NewsByID(ctx, news[0].Id); !reflect.DeepEqual(again.Media, media) {
+		t.Errorf("NewsByID после изменения копии: %+v", again.Media)
+	}
+}
+
+func testNewsCollapse(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	id, err := db.AddSource(ctx, storage.Source{Title: "Пример", Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/rss"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	const fp uint64 = 0xF0F0F0F0F0F0F0F0
+	items := []storage.NewsShortDetailed{
+		{Title: "a", PubTime: 1000, Fingerprint: fp},
+		{Title: "b", PubTime: 3000, Fingerprint: fp ^ 0b111, Source: &storage.NewsSource{Id: id}}, // Похожа на a
+		{Title: "c", PubTime: 2000, Fingerprint: ^fp},                                             // Не похожа
+		{Title: "d", PubTime: 2500},                                                               // Без отпечатка
+		{Title: "e", PubTime: 3000 + storage.ClusterWindow + 1, Fingerprint: fp},                  // Похожа, но позже окна
+		{Title: "f", PubTime: 1500, Fingerprint: fp ^ (1<<(storage.ClusterDistance+1)-1)<<32},     // Дальше порога
+		{Title: "g", PubTime: 500, Fingerprint: fp ^ 0b1, Source: &storage.NewsSource{Id: id}},    // Похожа на a
+	}
+	for i, n := range items {
+		n.Link = fmt.Sprintf("https://example.com/collapse/%d", i)
+		n.Content = "content"
+		if _, err := db.AddNews(ctx, n); err != nil {
+			t.Fatalf("AddNews(%s): %v", n.Title, err)
+		}
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10, Sort: storage.SortId, Asc: true})
+	if err != nil || count != len(items) {
+		t.Fatalf("News: %d, %v", count, err)
+	}
+	clusters := map[string]int{}
+	for _, n := range news {
+		clusters[n.Title] = n.ClusterId
+		if n.ClusterSize != 0 || n.Alternates != nil {
+			t.Errorf("News без группировки вернул группу %+v", n)
+		}
+	}
+	if clusters["a"] != news[0].Id || clusters["b"] != news[0].Id || clusters["g"] != news[0].Id {
+		t.Errorf("похожие новости в разных группах: %v", clusters)
+	}
+	for _, title := range []string{"c", "d", "e", "f"} {
+		if clusters[title] == clusters["a"] {
+			t.Errorf("новость %s попала в группу a: %v", title, clusters)
+		}
+	}
+
+	// Из группы отдается первая по порядку сортировки новость, в количестве учитываются группы
+	news, count, err = db.News(ctx, storage.NewsQuery{Limit: 10, Sort: storage.SortPubTime, Collapse: true})
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	if got := titles(news); count != 5 || got != "ebdcf" {
+		t.Fatalf("News(collapse) = %q, %d, ожидалось \"ebdcf\", 5", got, count)
+	}
+	b := news[1]
+	if b.ClusterSize != 3 || len(b.Alternates) != 2 || b.Alternates[0].Title != "a" || b.Alternates[1].Title != "g" {
+		t.Errorf("группа b: размер %d, другие новости %+v", b.ClusterSize, b.Alternates)
+	}
+	if a := b.Alternates[1]; a.Link != "https://example.com/collapse/6" || a.Source == nil || a.Source.Title != "Пример" {
+		t.Errorf("другая новость группы: %+v", a)
+	}
+	if news[0].ClusterSize != 1 || len(news[0].Alternates) != 0 {
+		t.Errorf("одиночная новость: размер %d, другие новости %+v", news[0].ClusterSize, news[0].Alternates)
+	}
+	// Отбор применяется до группировки
+	news, count, err = db.News(ctx, storage.NewsQuery{Limit: 10, Sort: storage.SortPubTime, Collapse: true, To: 2000})
+	if got := titles(news); err != nil || count != 3 || got != "cfa" {
+		t.Errorf("News(collapse, to) = %q, %d, %v", got, count, err)
+	}
+	// По курсору группа представлена самой новой новостью
+	page, more, err := db.NewsPage(ctx, nil, storage.NewsQuery{Limit: 2, Collapse: true})
+	if got := titles(page); err != nil || !more || got != "eb" || page[1].ClusterSize != 3 {
+		t.Fatalf("NewsPage(collapse) = %q, %v, %v", got, more, err)
+	}
+	cursor := &storage.Cursor{PubTime: page[1].PubTime, Id: page[1].Id}
+	page, more, err = db.NewsPage(ctx, cursor, storage.NewsQuery{Limit: 10, Collapse: true})
+	if got := titles(page); err != nil || more || got != "dcf" {
+		t.Errorf("NewsPage(collapse) после курсора = %q, %v, %v", got, more, err)
+	}
+}
+
+func testNewsRevisions(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	first, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/first.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	second, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/second.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	n := storage.NewsShortDetailed{
+		Title:   "Черновик",
+		Content: "Первая версия",
+		Body:    "<p>Первая версия</p>",
+		PubTime: 1700000000,
+		Link:    "https://example.com/edited",
+		Source:  &storage.NewsSource{Id: first},
+	}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Fatalf("AddNews = %v, %v", result, err)
+	}
+	news, _, err := db.News(ctx, storage.NewsQuery{Limit: 1})
+	if err != nil || len(news) != 1 || news[0].UpdatedAt != 0 {
+		t.Fatalf("News: %+v, %v", news, err)
+	}
+	id := news[0].Id
+	// Повтор без изменений и та же новость из другого источника не меняют новость
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsUnchanged {
+		t.Errorf("AddNews(без изменений) = %v, %v", result, err)
+	}
+	other := n
+	other.Title, other.Source = "Перепечатка", &storage.NewsSource{Id: second}
+	if result, err := db.AddNews(ctx, other); err != nil || result != storage.NewsUnchanged {
+		t.Errorf("AddNews(другой источник) = %v, %v", result, err)
+	}
+	// Исправленный заголовок, затем дополненный текст
+	edited := n
+	edited.Title = "Заголовок"
+	if result, err := db.AddNews(ctx, edited); err != nil || result != storage.NewsUpdated {
+		t.Fatalf("AddNews(заголовок) = %v, %v", result, err)
+	}
+	edited.Content, edited.Body = "Вторая версия", "<p>Вторая версия</p><p>Подробности</p>"
+	if result, err := db.AddNews(ctx, edited); err != nil || result != storage.NewsUpdated {
+		t.Fatalf("AddNews(текст) = %v, %v", result, err)
+	}
+	got, err := db.NewsByID(ctx, id)
+	if err != nil {
+		t.Fatalf("NewsByID: %v", err)
+	}
+	if got.Title != edited.Title || got.Content != edited.Content || got.Body != edited.Body || got.UpdatedAt == 0 {
+		t.Errorf("NewsByID после изменения = %+v", got)
+	}
+	revisions, err := db.NewsRevisions(ctx, id)
+	if err != nil {
+		t.Fatalf("NewsRevisions: %v", err)
+	}
+	if len(revisions) != 2 ||
+		revisions[0].Title != "Заголовок" || revisions[0].Content != "Первая версия" ||
+		revisions[1].Title != "Черновик" || revisions[1].Body != "<p>Первая версия</p>" ||
+		revisions[0].ReplacedAt < revisions[1].ReplacedAt || revisions[0].Id <= revisions[1].Id {
+		t.Errorf("NewsRevisions = %+v", revisions)
+	}
+	if revisions, err := db.NewsRevisions(ctx, id+1000); err != nil || len(revisions) != 0 {
+		t.Errorf("NewsRevisions(неизвестная новость) = %+v, %v", revisions, err)
+	}
+	// Удаление источника удаляет историю его новостей
+	if err := db.DeleteSource(ctx, first); err != nil {
+		t.Fatalf("DeleteSource: %v", err)
+	}
+	if revisions, err := db.NewsRevisions(ctx, id); err != nil || len(revisions) != 0 {
+		t.Errorf("NewsRevisions после удаления источника = %+v, %v", revisions, err)
+	}
+}
+
+func testLegacyNewsRevisions(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	// Новость, сохраненная до появления источников, - без источника
+	n := storage.NewsShortDetailed{Title: "Черновик", Content: "Первая версия", PubTime: 1700000000, Link: "https://example.com/legacy"}
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Fatalf("AddNews = %v, %v", result, err)
+	}
+	news, _, err := db.News(ctx, storage.NewsQuery{Limit: 1})
+	if err != nil || len(news) != 1 {
+		t.Fatalf("News: %+v, %v", news, err)
+	}
+	id := news[0].Id
+	first, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/first.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	second, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/second.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	// Исправленная новость из источника обновляется и становится новостью этого источника
+	edited := n
+	edited.Title, edited.Source = "Заголовок", &storage.NewsSource{Id: first}
+	if result, err := db.AddNews(ctx, edited); err != nil || result != storage.NewsUpdated {
+		t.Fatalf("AddNews(заголовок) = %v, %v", result, err)
+	}
+	got, err := db.NewsByID(ctx, id)
+	if err != nil {
+		t.Fatalf("NewsByID: %v", err)
+	}
+	if got.Title != "Заголовок" || got.Source == nil || got.Source.Id != first || got.UpdatedAt == 0 {
+		t.Errorf("NewsByID после изменения = %+v", got)
+	}
+	if revisions, err := db.NewsRevisions(ctx, id); err != nil || len(revisions) != 1 || revisions[0].Title != "Черновик" {
+		t.Errorf("NewsRevisions = %+v, %v", revisions, err)
+	}
+	// Теперь та же новость из другого источника новость не меняет
+	other := edited
+	other.Title, other.Source = "Перепечатка", &storage.NewsSource{Id: second}
+	if result, err := db.AddNews(ctx, other); err != nil || result != storage.NewsUnchanged {
+		t.Errorf("AddNews(другой источник) = %v, %v", result, err)
+	}
+	// Неизмененная новость без источника получает источник, но не меняется
+	n.Link = "https://example.com/legacy-unchanged"
+	if result, err := db.AddNews(ctx, n); err != nil || result != storage.NewsInserted {
+		t.Fatalf("AddNews = %v, %v", result, err)
+	}
+	unchanged := n
+	unchanged.Source = &storage.NewsSource{Id: second}
+	if result, err := db.AddNews(ctx, unchanged); err != nil || result != storage.NewsUnchanged {
+		t.Errorf("AddNews(без изменений) = %v, %v", result, err)
+	}
+	news, _, err = db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("News: %v", err)
+	}
+	for _, item := range news {
+		if item.Link == n.Link && (item.Source == nil || item.Source.Id != second || item.UpdatedAt != 0) {
+			t.Errorf("неизмененная новость = %+v", item)
+		}
+	}
+}
+
+func testAddNewsBatch(t *testing.T, db storage.Store) {
+	ctx := context.Background()
+	source, err := db.AddSource(ctx, storage.Source{Enabled: true, FeedState: storage.FeedState{URL: "https://example.com/batch.xml"}})
+	if err != nil {
+		t.Fatalf("AddSource: %v", err)
+	}
+	stored := storage.NewsShortDetailed{
+		Title:   "Сохраненная",
+		Content: "content",
+		PubTime: 1700000000,
+		Link:    "https://example.com/stored",
+		Source:  &storage.NewsSource{Id: source},
+	}
+	edited := stored
+	edited.Link = "https://example.com/edited"
+	for _, n := range []storage.NewsShortDetailed{stored, edited} {
+		if _, err := db.AddNews(ctx, n); err != nil {
+			t.Fatalf("AddNews: %v", err)
+		}
+	}
+	edited.Title = "Исправленная"
+	const fp = 0x0123456789abcdef
+	batch := []storage.NewsShortDetailed{
+		{
+			Title:       "Первая",
+			Content:     "content",
+			PubTime:     1700000100,
+			Link:        "https://example.com/first",
+			Source:      &storage.NewsSource{Id: source},
+			Media:       []storage.Media{{URL: "https://example.com/first.jpg", Type: "image/jpeg"}},
+			Fingerprint: fp,
+		},
+		stored,
+		edited,
+		{Title: "", Content: "content", PubTime: 1700000200, Link: "https://example.com/untitled"},
+		// Похожая на первую новость из того же пакета попадает в ее группу
+		{Title: "Вторая", Content: "content", PubTime: 1700000300, Link: "https://example.com/second", Fingerprint: fp ^ 1},
+		// Повтор ссылки внутри пакета
+		{Title: "Первая", Content: "content", PubTime: 1700000100, Link: "http://example.com/first/"},
+	}
+	results, err := db.AddNewsBatch(ctx, batch)
+	if err != nil {
+		t.Fatalf("AddNewsBatch: %v", err)
+	}
+	want := []string{
+		storage.NewsInserted,
+		storage.NewsUnchanged,
+		storage.NewsUpdated,
+		storage.NewsFailed,
+		storage.NewsInserted,
+		storage.NewsUnchanged,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("AddNewsBatch вернул %d результатов, ожидалось %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.Result != want[i] || (r.Err != nil) != (want[i] == storage.NewsFailed) {
+			t.Errorf("результат %d = %+v, ожидалось %s", i, r, want[i])
+		}
+	}
+	news, count, err := db.News(ctx, storage.NewsQuery{Limit: 10})
+	if err != nil || count != 4 {
+		t.Fatalf("News: %d новостей, %v", count, err)
+	}
+	if got := titles(news); got != "ВтораяПерваяИсправленнаяСохраненная" {
+		t.Errorf("News = %s", got)
+	}
+	first, second := news[1], news[0]
+	if len(first.Media) != 1 || first.Media[0].URL != "https://example.com/first.jpg" {
+		t.Errorf("вложения первой новости = %+v", first.Media)
+	}
+	if first.ClusterId != first.Id || second.ClusterId != first.Id {
+		t.Errorf("группы новостей пакета: %d, %d, ожидалась %d", first.ClusterId, second.ClusterId, first.Id)
+	}
+	// Новость с неизвестным источником не мешает записи остальных
+	results, err = db.AddNewsBatch(ctx, []storage.NewsShortDetailed{
+		{Title: "Без источника", Content: "content", PubTime: 1700000400, Link: "https://example.com/orphan", Source: &storage.NewsSource{Id: source + 1000}},
+		{Title: "Третья", Content: "content", PubTime: 1700000500, Link: "https://example.com/third"},
+	})
+	if err != nil {
+		t.Fatalf("AddNewsBatch: %v", err)
+	}
+	if len(results) != 2 || results[0].Result != storage.NewsFailed || results[0].Err == nil || results[1].Result != storage.NewsInserted {
+		t.Errorf("AddNewsBatch с неизвестным источником = %+v", results)
+	}
+	if results, err := db.AddNewsBatch(ctx, nil); err != nil || len(results) != 0 {
+		t.Errorf("AddNewsBatch(nil) = %+v, %v", results, err)
+	}
+}
+
+func testCanceledContext(t *testing.T, db storage.Store) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.AddNews(ctx, storage.NewsShortDetailed{Title: "Отмена", Content: "content", Link: "https://example.com/cancel"}); err == nil {
+		t.Error("AddNews с отмененным контекстом не вернул ошибку")
+	}
+	if _, err := db.AddNewsBatch(ctx, []storage.NewsShortDetailed{{Title: "Отмена", Content: "content", Link: "https://example.com/cancel"}}); err == nil {
+		t.Error("AddNewsBatch с отмененным контекстом не вернул ошибку")
+	}
+	if _, _, err := db.News(ctx, storage.NewsQuery{Limit: 10}); err == nil {
+		t.Error("News с отмененным контекстом не вернул ошибку")
+	}
+	if _, err := db.Dictionary(ctx); err == nil {
+		t.Error("Dictionary с отмененным контекстом не вернул ошибку")
+	}
+	news, count, err := db.News(context.Background(), storage.NewsQuery{Limit: 10})
+	if err != nil || count != 0 {
+		t.Errorf("после отмены: %v, %v", news, err)
+	}
+}